@@ -4,10 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bwilson/verkounter/internal/counter"
 	"github.com/bwilson/verkounter/internal/output"
@@ -17,10 +20,36 @@ import (
 )
 
 type WorkResult struct {
-	FolderName string
-	SeriesName string
-	WordCount  int
-	Error      error
+	FolderName  string
+	ProjectKey  string // Key of the project's history: its ID, or its sanitized name when it has none
+	LegacyKey   string // Key of the project's history before it had an ID
+	SeriesName  string
+	WordCount   int
+	Excluded    int                               // Words hidden by exclusion markers
+	Method      string                            // Name of the counting strategy used
+	Extraction  string                            // Markdown extraction mode used
+	Files       map[string]int                    // Words per file
+	Sections    []processor.Section               // Words per chapter, when chapter counting is enabled
+	FileHistory bool                              // Whether to record Files in the project's file history
+	Screenplay  *processor.Screenplay             // Screenplay metrics, when the project has Fountain files
+	Breakdown   *processor.Breakdown              // Words per status, POV and tag, when files set them in their frontmatter
+	Categories  map[string]int                    // Words per category, e.g. manuscript or notes
+	Target      int                               // Word count the project aims for, from its .verkount file
+	Deadline    scanner.Date                      // Day the target is due
+	Status      string                            // Where the project stands, e.g. drafting
+	Tags        []string                          // Labels set in the project's .verkount file
+	FileErrors  []processor.FileError             // Files that could not be read
+	Encodings   map[string]processor.FileEncoding // Text files that were not plain UTF-8
+	Skipped     []processor.SkippedFile           // Files left out as binary content or for their size
+	Error       error
+	ConfigError bool // Whether Error came from the project's settings rather than its files
+}
+
+// runOptions holds the command line settings shared by all workers
+type runOptions struct {
+	counter     counter.Counter    // Strategy for projects that don't configure one
+	fileHistory bool               // Record per-file history for every project
+	maxFileSize processor.FileSize // Size ceiling for projects that don't set one
 }
 
 func printUsage() {
@@ -30,6 +59,11 @@ by scanning directories for .verkount marker files and processing Markdown conte
 Usage:
   verkounter [directory]     Scan directory for .verkount projects (default: ~/Documents)
   verkounter --stats         Display writing statistics
+  verkounter --stats --project NAME
+                             Display the file breakdown of a project
+  verkounter --counter NAME  Count words with the named strategy
+  verkounter --list-files [directory]
+                             List the files each project would count
   verkounter --help          Show this help message
 
 Arguments:
@@ -38,12 +72,25 @@ Arguments:
 
 Options:
   --stats                    Display detailed writing statistics
+  --project NAME             With --stats, show per-file counts for a project
+  --file-history             Record per-file word counts for every project
+  --list-files               List the files each project would count, after
+                            .verkountignore and include/exclude globs, and exit
+  --max-file-size SIZE       Skip files larger than SIZE, e.g. 20MB (default
+                            100MB, 0 for no limit)
+  --strict                   Record nothing when any project or file could not
+                            be read, so a failed read never looks like deleted text
+  --counter NAME             Counting strategy for projects that don't set one
+                            in their .verkount file: heuristic (default, 6
+                            characters per word), unicode-words, cjk, auto,
+                            characters or characters-without-spaces
   --help, -h                 Show help information
 
 Output files:
   Stats are stored in ~/.local/share/verkounter/
   - verkount_stats.yaml      Main statistics file with daily word counts
   - series/*_stats.yaml      Per-series statistics files
+  - files/*_files.yaml       Per-project file histories (when enabled)
 
 For more information, see: https://github.com/bwilson/verkounter`)
 }
@@ -51,6 +98,13 @@ For more information, see: https://github.com/bwilson/verkounter`)
 func main() {
 	// Parse command line flags
 	statsFlag := flag.Bool("stats", false, "Display writing statistics")
+	counterFlag := flag.String("counter", counter.DefaultCounter, "Default counting strategy")
+	projectFlag := flag.String("project", "", "Project to show per-file statistics for")
+	fileHistoryFlag := flag.Bool("file-history", false, "Record per-file word counts")
+	strictFlag := flag.Bool("strict", false, "Record nothing when a file could not be read")
+	listFlag := flag.Bool("list-files", false, "List the files that would be counted")
+	maxFileSize := processor.DefaultMaxFileSize
+	flag.Var(&maxFileSize, "max-file-size", "Skip files larger than this size")
 	helpFlag := flag.Bool("help", false, "Show help information")
 	flag.BoolVar(helpFlag, "h", false, "Show help information (shorthand)")
 	flag.Usage = printUsage
@@ -62,6 +116,11 @@ func main() {
 		return
 	}
 
+	defaultCounter, err := counter.Lookup(*counterFlag)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatalf("Error getting home directory: %v", err)
@@ -69,6 +128,10 @@ func main() {
 
 	// If --stats flag is provided, show statistics and exit
 	if *statsFlag {
+		if *projectFlag != "" {
+			showFileStatistics(*projectFlag)
+			return
+		}
 		showStatistics("")
 		return
 	}
@@ -78,7 +141,7 @@ func main() {
 	args := flag.Args()
 	if len(args) > 0 {
 		scanPath = args[0]
-		
+
 		// Handle current directory
 		if scanPath == "." {
 			scanPath, err = os.Getwd()
@@ -122,6 +185,22 @@ func main() {
 	}
 
 	fmt.Printf("Found %d folders to process\n", len(folders))
+	for _, folder := range folders {
+		if folder.Collision != "" {
+			fmt.Printf("Note: %s\n", folder.Collision)
+		}
+	}
+
+	if *listFlag {
+		listProjectFiles(folders)
+		return
+	}
+
+	// Projects are given an ID before they are first counted, so their
+	// history survives a rename
+	for _, err := range scanner.AssignProjectIDs(folders, scanPath) {
+		fmt.Printf("Warning: Could not give a project an ID, its history stays under its name: %v\n", err)
+	}
 
 	numWorkers := 4
 	if len(folders) < numWorkers {
@@ -135,7 +214,11 @@ func main() {
 
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go worker(folderChan, resultChan, &wg)
+		go worker(folderChan, resultChan, runOptions{
+			counter:     defaultCounter,
+			fileHistory: *fileHistoryFlag,
+			maxFileSize: maxFileSize,
+		}, &wg)
 	}
 
 	for _, folder := range folders {
@@ -148,34 +231,121 @@ func main() {
 		close(resultChan)
 	}()
 
-	results := make(map[string]int)
-	seriesResults := make(map[string]map[string]int)
+	results := make(map[string]output.ProjectResult)
+	seriesResults := make(map[string]map[string]output.ProjectResult)
 	errorCount := 0
+	configErrorCount := 0
+	fileErrorCount := 0
+	skippedCount := 0
+	var histories []WorkResult // File histories to record once the run is accepted
 
 	for result := range resultChan {
 		if result.Error != nil {
 			fmt.Printf("Error processing %s: %v\n", result.FolderName, result.Error)
 			errorCount++
+			if result.ConfigError {
+				configErrorCount++
+			}
 		} else {
 			sanitizedName := counter.SanitizeFolderName(result.FolderName)
-			results[sanitizedName] = result.WordCount
-			fmt.Printf("  %s: %d words\n", sanitizedName, result.WordCount)
+			projectResult := output.ProjectResult{
+				Name:       sanitizedName,
+				LegacyKey:  result.LegacyKey,
+				Series:     result.SeriesName,
+				Words:      result.WordCount,
+				Method:     result.Method,
+				Extraction: result.Extraction,
+				Categories: result.Categories,
+			}
+			if result.Screenplay != nil {
+				projectResult.Screenplay = &output.ScreenplayStats{
+					Scenes:        result.Screenplay.Scenes,
+					Pages:         result.Screenplay.Pages,
+					DialogueWords: result.Screenplay.DialogueWords,
+					ActionWords:   result.Screenplay.ActionWords,
+				}
+			}
+			if result.Breakdown != nil {
+				projectResult.Breakdown = &output.BreakdownStats{
+					Status: result.Breakdown.Status,
+					POV:    result.Breakdown.POV,
+					Tags:   result.Breakdown.Tags,
+				}
+			}
+			results[result.ProjectKey] = projectResult
+
+			if result.FileHistory {
+				histories = append(histories, result)
+			}
+			if result.Excluded > 0 {
+				fmt.Printf("  %s: %d words (%d excluded)\n", sanitizedName, result.WordCount, result.Excluded)
+			} else {
+				fmt.Printf("  %s: %d words\n", sanitizedName, result.WordCount)
+			}
+			printProject(result, time.Now())
+			printCategories(result.Categories)
+			printSections(result.Sections)
+			if result.Screenplay != nil {
+				dialogue := int(result.Screenplay.DialogueRatio()*100 + 0.5)
+				fmt.Printf("    %.1f pages, %d scenes, %d%% dialogue / %d%% action\n",
+					result.Screenplay.Pages, result.Screenplay.Scenes, dialogue, 100-dialogue)
+			}
+			printBreakdown(result.Breakdown)
+			for _, fileErr := range result.FileErrors {
+				fmt.Printf("    Could not read %v\n", fileErr)
+			}
+			printGuessedEncodings(result.Encodings)
+			for _, skipped := range result.Skipped {
+				fmt.Printf("    Skipped %s: %s\n", skipped.Path, skipped.Reason)
+			}
+			skippedCount += len(result.Skipped)
+			fileErrorCount += len(result.FileErrors)
 
 			// Track results by series
 			if result.SeriesName != "" {
 				if seriesResults[result.SeriesName] == nil {
-					seriesResults[result.SeriesName] = make(map[string]int)
+					seriesResults[result.SeriesName] = make(map[string]output.ProjectResult)
 				}
-				seriesResults[result.SeriesName][result.FolderName] = result.WordCount
+				seriesResults[result.SeriesName][result.ProjectKey] = projectResult
 			}
 		}
 	}
 
+	// A project whose settings could not be read has no count, and recording
+	// the day without it would look like the project was deleted
+	if configErrorCount > 0 {
+		fmt.Printf("\nNot recording stats: the settings of %d projects could not be read, fix them and run again\n", configErrorCount)
+		os.Exit(1)
+	}
+
+	// A file that could not be read would look like deleted text in the day's delta
+	if *strictFlag && (errorCount > 0 || fileErrorCount > 0) {
+		fmt.Printf("\nStrict mode: not recording stats, %d projects and %d files could not be read\n", errorCount, fileErrorCount)
+		os.Exit(1)
+	}
+
 	if len(results) == 0 {
 		fmt.Println("No results to save.")
 		return
 	}
 
+	// A file history kept under a name several projects share now held
+	// whichever was counted last, so none of them takes it over
+	legacyKeys := make(map[string]int)
+	for _, result := range results {
+		legacyKeys[result.LegacyKey]++
+	}
+	for _, result := range histories {
+		sanitizedName := counter.SanitizeFolderName(result.FolderName)
+		legacyKey := result.LegacyKey
+		if legacyKeys[legacyKey] > 1 {
+			legacyKey = ""
+		}
+		if err := output.WriteFileStats(result.ProjectKey, sanitizedName, legacyKey, result.Files, chapterCounts(result.Sections)); err != nil {
+			fmt.Printf("Warning: Could not write file history for %s: %v\n", sanitizedName, err)
+		}
+	}
+
 	// Write overall stats
 	err = output.WriteStats(results, scanPath)
 	if err != nil {
@@ -189,8 +359,8 @@ func main() {
 	}
 
 	total := 0
-	for _, count := range results {
-		total += count
+	for _, result := range results {
+		total += result.Words
 	}
 
 	// Determine XDG data directory for display message
@@ -205,19 +375,48 @@ func main() {
 			}
 		}
 	}
-	
+
 	fmt.Printf("\nStats saved to %s/verkounter/verkount_stats.yaml\n", dataHome)
 	fmt.Printf("Total words: %d\n", total)
 	if errorCount > 0 {
 		fmt.Printf("Errors encountered: %d\n", errorCount)
 	}
+	if fileErrorCount > 0 {
+		fmt.Printf("Files that could not be read: %d\n", fileErrorCount)
+	}
+	if skippedCount > 0 {
+		fmt.Printf("Files skipped as binary or oversized: %d\n", skippedCount)
+	}
 }
 
-func worker(folders <-chan scanner.VerkountFolder, results chan<- WorkResult, wg *sync.WaitGroup) {
+func worker(folders <-chan scanner.VerkountFolder, results chan<- WorkResult, opts runOptions, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for folder := range folders {
-		content, err := processor.ProcessMarkdownFiles(folder.Path)
+		wordCounter, err := projectCounter(folder, opts.counter)
+		if err != nil {
+			results <- WorkResult{
+				FolderName:  folder.Name,
+				SeriesName:  folder.Series,
+				Error:       err,
+				ConfigError: true,
+			}
+			continue
+		}
+
+		processorOpts, err := processorOptions(folder.Config, opts.maxFileSize)
+		if err != nil {
+			results <- WorkResult{
+				FolderName:  folder.Name,
+				SeriesName:  folder.Series,
+				Error:       err,
+				ConfigError: true,
+			}
+			continue
+		}
+		processorOpts.Counter = wordCounter
+
+		counts, err := processor.ProcessMarkdownFiles(folder.Path, processorOpts)
 		if err != nil {
 			results <- WorkResult{
 				FolderName: folder.Name,
@@ -227,17 +426,268 @@ func worker(folders <-chan scanner.VerkountFolder, results chan<- WorkResult, wg
 			continue
 		}
 
-		wordCount := counter.CountWords(content)
-
 		results <- WorkResult{
-			FolderName: folder.Name,
-			SeriesName: folder.Series,
-			WordCount:  wordCount,
-			Error:      nil,
+			FolderName:  folder.Name,
+			ProjectKey:  folder.Key,
+			LegacyKey:   folder.LegacyKey,
+			SeriesName:  folder.Series,
+			WordCount:   counts.Words,
+			Excluded:    counts.Excluded,
+			Method:      wordCounter.Name(),
+			Extraction:  processorOpts.Markdown.Mode(),
+			Files:       counts.Files,
+			Sections:    counts.Sections,
+			Screenplay:  counts.Screenplay,
+			Breakdown:   counts.Breakdown,
+			Categories:  counts.Categories,
+			FileErrors:  counts.Errors,
+			Encodings:   counts.Encodings,
+			Skipped:     counts.Skipped,
+			FileHistory: opts.fileHistory || folder.Config.FileHistory,
+			Target:      folder.Config.Target,
+			Deadline:    folder.Config.Deadline,
+			Status:      folder.Config.Status,
+			Tags:        folder.Config.Tags,
+			Error:       nil,
+		}
+	}
+}
+
+// listProjectFiles prints the files each project would count
+func listProjectFiles(folders []scanner.VerkountFolder) {
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Path < folders[j].Path })
+
+	for _, folder := range folders {
+		fmt.Printf("\n%s (%s):\n", folder.Name, folder.Path)
+		if folder.Err != nil {
+			fmt.Printf("  Error: %v\n", folder.Err)
+			continue
+		}
+
+		processorOpts, err := processorOptions(folder.Config, 0)
+		if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			continue
+		}
+		files, fileErrors, err := processor.ListFiles(folder.Path, processorOpts)
+		if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			continue
+		}
+
+		for _, file := range files {
+			fmt.Printf("  %s\n", file)
+		}
+		for _, fileErr := range fileErrors {
+			fmt.Printf("  Could not read %v\n", fileErr)
 		}
+		fmt.Printf("  %d files\n", len(files))
 	}
 }
 
+// printProject prints the status and tags of a project, and its progress
+// towards its target with the words a day still needed to meet its deadline
+func printProject(result WorkResult, now time.Time) {
+	var details []string
+	if result.Status != "" {
+		details = append(details, "status "+result.Status)
+	}
+	if len(result.Tags) > 0 {
+		details = append(details, "tags "+strings.Join(result.Tags, ", "))
+	}
+	if len(details) > 0 {
+		fmt.Printf("    %s\n", strings.Join(details, "; "))
+	}
+
+	if result.Target <= 0 {
+		return
+	}
+	progress := fmt.Sprintf("%d%% of %d words", result.WordCount*100/result.Target, result.Target)
+	if !result.Deadline.IsZero() {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		// The deadline itself is a day left to write
+		daysLeft := int(math.Round(result.Deadline.Sub(today).Hours()/24)) + 1
+		remaining := result.Target - result.WordCount
+		switch {
+		case remaining <= 0:
+			progress += fmt.Sprintf(", reached before %s", result.Deadline.Format("Jan 2, 2006"))
+		case daysLeft <= 0:
+			progress += fmt.Sprintf(", deadline %s has passed", result.Deadline.Format("Jan 2, 2006"))
+		default:
+			progress += fmt.Sprintf(", %d words a day until %s", (remaining+daysLeft-1)/daysLeft, result.Deadline.Format("Jan 2, 2006"))
+		}
+	}
+	fmt.Printf("    Target: %s\n", progress)
+}
+
+// printCategories prints the words in each category of a project that sorts
+// its files into categories, largest first
+func printCategories(categories map[string]int) {
+	if _, manuscript := categories[processor.DefaultCategory]; len(categories) == 0 || manuscript && len(categories) == 1 {
+		return
+	}
+
+	var names []string
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if categories[names[i]] != categories[names[j]] {
+			return categories[names[i]] > categories[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, categories[name])
+	}
+	fmt.Printf("    Categories: %s\n", strings.Join(parts, ", "))
+}
+
+// printSections prints the word count of each chapter with its share of the
+// chapter total, so unbalanced chapters stand out
+func printSections(sections []processor.Section) {
+	total := 0
+	for _, section := range sections {
+		total += section.Words
+	}
+
+	for _, section := range sections {
+		share := 0
+		if total > 0 {
+			share = section.Words * 100 / total
+		}
+		fmt.Printf("    %s%s: %d words (%d%%)\n", strings.Repeat("  ", max(section.Level-1, 0)), section.Label(), section.Words, share)
+	}
+}
+
+// printBreakdown prints the words per revision status, POV character and tag,
+// largest first
+func printBreakdown(breakdown *processor.Breakdown) {
+	if breakdown == nil {
+		return
+	}
+
+	for _, dimension := range []struct {
+		label  string
+		counts map[string]int
+	}{
+		{"Status", breakdown.Status},
+		{"POV", breakdown.POV},
+		{"Tags", breakdown.Tags},
+	} {
+		if len(dimension.counts) == 0 {
+			continue
+		}
+		var values []string
+		for value := range dimension.counts {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if dimension.counts[values[i]] != dimension.counts[values[j]] {
+				return dimension.counts[values[i]] > dimension.counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprintf("%s %d", value, dimension.counts[value])
+		}
+		fmt.Printf("    %s: %s\n", dimension.label, strings.Join(parts, ", "))
+	}
+}
+
+// printGuessedEncodings lists the files whose encoding had to be guessed, so
+// their counts can be checked
+func printGuessedEncodings(encodings map[string]processor.FileEncoding) {
+	var files []string
+	for file, encoding := range encodings {
+		if encoding.Guessed {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		fmt.Printf("    Guessed %s encoding for %s\n", encodings[file].Name, file)
+	}
+}
+
+// chapterCounts converts sections to the chapter list stored in the file history
+func chapterCounts(sections []processor.Section) []output.ChapterCount {
+	var chapters []output.ChapterCount
+	for _, section := range sections {
+		chapters = append(chapters, output.ChapterCount{Label: section.Label(), Words: section.Words})
+	}
+	return chapters
+}
+
+// projectCounter returns the counting strategy configured in the project's
+// .verkount file, falling back to the strategy chosen on the command line
+func projectCounter(folder scanner.VerkountFolder, defaultCounter counter.Counter) (counter.Counter, error) {
+	if folder.Err != nil {
+		return nil, folder.Err
+	}
+
+	wordCounter := defaultCounter
+	if folder.Config.Counter != "" {
+		var err error
+		if wordCounter, err = counter.Lookup(folder.Config.Counter); err != nil {
+			return nil, err
+		}
+	}
+
+	if folder.Config.CharactersPerWord != 0 {
+		return counter.WithCharactersPerWord(wordCounter, folder.Config.CharactersPerWord)
+	}
+
+	return wordCounter, nil
+}
+
+// processorOptions builds the options a project's files are processed with
+// from its .verkount file, falling back to the size limit chosen on the
+// command line
+func processorOptions(config scanner.ProjectConfig, defaultMaxFileSize processor.FileSize) (processor.Options, error) {
+	maxFileSize := defaultMaxFileSize
+	if config.MaxFileSize != "" {
+		var err error
+		if maxFileSize, err = processor.ParseFileSize(config.MaxFileSize); err != nil {
+			return processor.Options{}, fmt.Errorf("max_file_size: %v", err)
+		}
+	}
+
+	var categories processor.Categories
+	for _, category := range config.Categories {
+		categories = append(categories, processor.Category{Name: category.Name, Globs: category.Globs})
+	}
+
+	return processor.Options{
+		Markdown: processor.MarkdownOptions{
+			Raw:               config.Markdown.Raw,
+			CountCodeBlocks:   config.Markdown.CountCodeBlocks,
+			CountInlineCode:   config.Markdown.CountInlineCode,
+			CountAltText:      config.Markdown.CountAltText,
+			CountHTMLComments: config.Markdown.CountHTMLComments,
+			SkipTables:        config.Markdown.SkipTables,
+		},
+		Office: processor.OfficeOptions{
+			HeadersFooters: config.Office.HeadersFooters,
+		},
+		LaTeX: processor.LaTeXOptions{
+			SkipBibliography: config.LaTeX.SkipBibliography,
+			SkipCaptions:     config.LaTeX.SkipCaptions,
+		},
+		ChapterLevel: config.ChapterLevel,
+		Extensions:   config.Extensions,
+		MaxFileSize:  maxFileSize,
+		Include:      config.Include,
+		Exclude:      config.Exclude,
+		Categories:   categories,
+	}, nil
+}
+
 func showStatistics(path string) {
 	// Load the stats file from XDG data directory
 	statsData, err := stats.LoadStats(path)
@@ -247,4 +697,14 @@ func showStatistics(path string) {
 
 	// Calculate and display statistics
 	stats.CalculateStats(statsData)
+	stats.ShowFilesChangedToday()
+}
+
+func showFileStatistics(project string) {
+	fileStats, err := stats.LoadFileStats(counter.SanitizeFolderName(project))
+	if err != nil {
+		log.Fatalf("Error loading file history: %v", err)
+	}
+
+	stats.ShowFileStats(project, fileStats)
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bwilson/verkounter/internal/scanner"
+)
+
+// captureOutput returns what fn prints to standard output
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	return string(out)
+}
+
+func TestPrintProject(t *testing.T) {
+	now := time.Date(2026, 3, 1, 21, 30, 0, 0, time.Local)
+	day := func(year int, month time.Month, d int) scanner.Date {
+		return scanner.Date{Time: time.Date(year, month, d, 0, 0, 0, 0, time.Local)}
+	}
+
+	tests := []struct {
+		name     string
+		result   WorkResult
+		expected string
+	}{
+		{
+			name:     "No details",
+			result:   WorkResult{WordCount: 1200},
+			expected: "",
+		},
+		{
+			name:     "Status and tags",
+			result:   WorkResult{Status: "drafting", Tags: []string{"thriller", "nanowrimo"}},
+			expected: "    status drafting; tags thriller, nanowrimo\n",
+		},
+		{
+			name:     "Target without a deadline",
+			result:   WorkResult{WordCount: 4500, Target: 90000},
+			expected: "    Target: 5% of 90000 words\n",
+		},
+		{
+			name:     "Deadline ahead",
+			result:   WorkResult{WordCount: 4500, Target: 90000, Deadline: day(2026, 3, 10)},
+			expected: "    Target: 5% of 90000 words, 8550 words a day until Mar 10, 2026\n",
+		},
+		{
+			name:     "Deadline today",
+			result:   WorkResult{WordCount: 89000, Target: 90000, Deadline: day(2026, 3, 1)},
+			expected: "    Target: 98% of 90000 words, 1000 words a day until Mar 1, 2026\n",
+		},
+		{
+			name:     "Deadline passed",
+			result:   WorkResult{WordCount: 89000, Target: 90000, Deadline: day(2026, 2, 28)},
+			expected: "    Target: 98% of 90000 words, deadline Feb 28, 2026 has passed\n",
+		},
+		{
+			name:     "Target reached",
+			result:   WorkResult{WordCount: 91000, Target: 90000, Deadline: day(2026, 3, 10), Status: "revising"},
+			expected: "    status revising\n    Target: 101% of 90000 words, reached before Mar 10, 2026\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := captureOutput(t, func() { printProject(tt.result, now) }); out != tt.expected {
+				t.Errorf("printProject() printed %q, want %q", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrintProjectAcrossDaylightSaving(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+
+	// Clocks go forward on March 8, 2026, so the days until the deadline
+	// are an hour short of whole days
+	now := time.Date(2026, 3, 7, 9, 0, 0, 0, location)
+	result := WorkResult{
+		WordCount: 0,
+		Target:    3000,
+		Deadline:  scanner.Date{Time: time.Date(2026, 3, 9, 0, 0, 0, 0, location)},
+	}
+
+	expected := "    Target: 0% of 3000 words, 1000 words a day until Mar 9, 2026\n"
+	if out := captureOutput(t, func() { printProject(result, now) }); out != expected {
+		t.Errorf("printProject() printed %q, want %q", out, expected)
+	}
+}
@@ -1,26 +1,176 @@
 package counter
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 )
 
+// CharactersPerWord is the default ratio used by the heuristic counter
 const CharactersPerWord = 6
 
+// DefaultCounter names the strategy used when neither the command line nor
+// the project selects one
+const DefaultCounter = "heuristic"
+
 type Result struct {
 	FolderName string
 	WordCount  int
 }
 
-func CountWords(content string) int {
-	charCount := len(strings.TrimSpace(content))
+// Counter is a named word counting strategy
+type Counter interface {
+	// Name returns the name the strategy is registered under
+	Name() string
+	// Count returns the number of words in content
+	Count(content string) int
+	// NewTally returns a Tally that counts streamed text the same way Count does
+	NewTally() Tally
+}
+
+var registry = make(map[string]Counter)
+
+func init() {
+	Register(Heuristic{CharactersPerWord: CharactersPerWord})
+	Register(UnicodeWords{})
+	Register(CJK{})
+	Register(Characters{})
+	Register(Characters{WithoutSpaces: true})
+	Register(Auto{Fallback: Heuristic{CharactersPerWord: CharactersPerWord}})
+}
+
+// Register makes a counting strategy available under its name, replacing any
+// strategy previously registered under the same name
+func Register(c Counter) {
+	registry[c.Name()] = c
+}
+
+// Lookup returns the counting strategy registered under name
+func Lookup(name string) (Counter, error) {
+	if c, ok := registry[strings.TrimSpace(name)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown counting strategy %q (want one of %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all registered strategies in sorted order
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithCharactersPerWord returns c with its heuristic ratio replaced by n.
+// Only the heuristic strategy and the heuristic fallback of auto have a ratio.
+func WithCharactersPerWord(c Counter, n int) (Counter, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("characters per word must be positive, got %d", n)
+	}
+
+	switch c := c.(type) {
+	case Heuristic:
+		return Heuristic{CharactersPerWord: n}, nil
+	case Auto:
+		if _, ok := c.Fallback.(Heuristic); ok {
+			return Auto{Fallback: Heuristic{CharactersPerWord: n}}, nil
+		}
+	}
+	return nil, fmt.Errorf("characters per word does not apply to the %s strategy", c.Name())
+}
+
+// Heuristic estimates words by dividing the character count by a fixed ratio
+type Heuristic struct {
+	CharactersPerWord int
+}
+
+func (Heuristic) Name() string { return "heuristic" }
+
+func (h Heuristic) Count(content string) int { return countString(h, content) }
+
+func (h Heuristic) NewTally() Tally {
+	return &trimmedTally{
+		units: func(r rune, size int) int { return size },
+		finish: func(charCount int) int {
+			if charCount == 0 {
+				return 0
+			}
+			return (charCount + h.CharactersPerWord - 1) / h.CharactersPerWord
+		},
+	}
+}
+
+// UnicodeWords counts words on Unicode word boundaries
+type UnicodeWords struct{}
+
+func (UnicodeWords) Name() string { return "unicode-words" }
+
+func (UnicodeWords) Count(content string) int { return CountUnicodeWords(content) }
+
+func (UnicodeWords) NewTally() Tally { return &wordTally{} }
+
+// CJK counts CJK characters, kana and Hangul syllables as one unit each and
+// the remaining text as Unicode words
+type CJK struct{}
+
+func (CJK) Name() string { return "cjk" }
 
-	if charCount == 0 {
-		return 0
+func (CJK) Count(content string) int { return CountCJK(content) }
+
+func (CJK) NewTally() Tally { return &wordTally{field: fieldCounter{cjk: true}} }
+
+// Characters counts characters instead of words. Line breaks are never
+// counted; other whitespace is counted unless WithoutSpaces is set.
+type Characters struct {
+	WithoutSpaces bool
+}
+
+func (c Characters) Name() string {
+	if c.WithoutSpaces {
+		return "characters-without-spaces"
 	}
+	return "characters"
+}
+
+func (c Characters) Count(content string) int { return countString(c, content) }
 
-	wordCount := (charCount + CharactersPerWord - 1) / CharactersPerWord
-	return wordCount
+func (c Characters) NewTally() Tally {
+	return &trimmedTally{
+		units: func(r rune, size int) int {
+			if r == '\n' || r == '\r' || c.WithoutSpaces && unicode.IsSpace(r) {
+				return 0
+			}
+			return 1
+		},
+		finish: func(count int) int { return count },
+	}
+}
+
+// Auto uses the CJK strategy for text that is mostly CJK and Fallback otherwise
+type Auto struct {
+	Fallback Counter
+}
+
+func (Auto) Name() string { return "auto" }
+
+func (a Auto) Count(content string) int {
+	if IsMostlyCJK(content) {
+		return CountCJK(content)
+	}
+	return a.Fallback.Count(content)
+}
+
+func (a Auto) NewTally() Tally {
+	return &autoTally{cjk: CJK{}.NewTally(), fallback: a.Fallback.NewTally()}
+}
+
+// CountWords estimates words using the default heuristic ratio
+func CountWords(content string) int {
+	return Heuristic{CharactersPerWord: CharactersPerWord}.Count(content)
 }
 
 func SanitizeFolderName(name string) string {
@@ -1,7 +1,9 @@
 package counter
 
 import (
+	"strings"
 	"testing"
+	"unicode"
 )
 
 func TestCountWords(t *testing.T) {
@@ -29,6 +31,127 @@ func TestCountWords(t *testing.T) {
 	}
 }
 
+func TestCountUnicodeWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"Empty string", "", 0},
+		{"Whitespace only", " \t\n ", 0},
+		{"Simple sentence", "The quick brown fox.", 4},
+		{"Accented letters", "Café naïve résumé", 3},
+		{"Em-dash separates", "one—two – three", 3},
+		{"Curly quotes", "“Hello,” she said.", 3},
+		{"Hyphenated compound", "a well-known twenty-one", 3},
+		{"Contractions", "don't won’t O'Brien's", 3},
+		{"Numbers", "3,000.50 dollars at 10:30", 4},
+		{"URL", "see https://example.com/a-b?c=d now", 3},
+		{"Email", "mail me@example.com today", 3},
+		{"Punctuation only", "--- *** ...", 0},
+		{"Slash separates", "and/or", 2},
+		{"Trailing hyphen", "pre- and post-war", 3},
+		{"Invalid byte after a word", "caf\xe9", 1},
+		{"Uppercase URL", "WWW.EXAMPLE.COM/A-B", 1},
+		{"Mailto", "mailto:me", 1},
+		{"At sign first", "@example.com", 1},
+		{"Address ending in a dot", "me@example.", 2},
+		{"Token longer than 64 KiB", strings.Repeat("a", 100*1024), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CountUnicodeWords(tt.content)
+			if result != tt.expected {
+				t.Errorf("CountUnicodeWords(%q) = %d, want %d", tt.content, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountCJK(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"Empty string", "", 0},
+		{"Chinese", "我爱写作。", 4},
+		{"Japanese kana and kanji", "こんにちは、世界！", 7},
+		{"Prolonged sound mark", "コーヒー", 4},
+		{"Korean", "안녕 하세요", 5},
+		{"Embedded Latin", "私はGo言語が好きです", 10},
+		{"Latin only", "Hello, world", 2},
+		{"Decomposed dakuten", "か\u3099", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CountCJK(tt.content)
+			if result != tt.expected {
+				t.Errorf("CountCJK(%q) = %d, want %d", tt.content, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAutoCounter(t *testing.T) {
+	auto, err := Lookup("auto")
+	if err != nil {
+		t.Fatalf("Lookup(auto) failed: %v", err)
+	}
+
+	japanese := "吾輩は猫である。名前はまだ無い。"
+	if got, want := auto.Count(japanese), CountCJK(japanese); got != want {
+		t.Errorf("auto.Count on Japanese = %d, want %d", got, want)
+	}
+
+	english := "The cat, whose name was 猫, sat on the mat."
+	if got, want := auto.Count(english), CountWords(english); got != want {
+		t.Errorf("auto.Count on English = %d, want %d", got, want)
+	}
+}
+
+func TestCharacters(t *testing.T) {
+	content := " Café au lait\nnoir "
+
+	if got := (Characters{}).Count(content); got != 16 {
+		t.Errorf("Characters.Count(%q) = %d, want 16", content, got)
+	}
+	if got := (Characters{WithoutSpaces: true}).Count(content); got != 14 {
+		t.Errorf("Characters{WithoutSpaces}.Count(%q) = %d, want 14", content, got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"heuristic", "unicode-words", "cjk", "characters", "characters-without-spaces", "auto"} {
+		c, err := Lookup(name)
+		if err != nil || c.Name() != name {
+			t.Errorf("Lookup(%q) = %v, %v", name, c, err)
+		}
+	}
+
+	if _, err := Lookup("bogus"); err == nil {
+		t.Error("Lookup(\"bogus\") should fail")
+	}
+}
+
+func TestWithCharactersPerWord(t *testing.T) {
+	heuristic, _ := Lookup("heuristic")
+	c, err := WithCharactersPerWord(heuristic, 5)
+	if err != nil {
+		t.Fatalf("WithCharactersPerWord failed: %v", err)
+	}
+	if got := c.Count("abcdefghij"); got != 2 {
+		t.Errorf("Count with 5 characters per word = %d, want 2", got)
+	}
+
+	cjk, _ := Lookup("cjk")
+	if _, err := WithCharactersPerWord(cjk, 5); err == nil {
+		t.Error("WithCharactersPerWord should reject the cjk strategy")
+	}
+}
+
 func TestSanitizeFolderName(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -53,3 +176,85 @@ func TestSanitizeFolderName(t *testing.T) {
 		})
 	}
 }
+
+// referenceCount counts content the way each strategy did on whole strings
+// before counting was streamed, without going through a Tally
+func referenceCount(c Counter, content string) int {
+	switch c := c.(type) {
+	case Heuristic:
+		charCount := len(strings.TrimSpace(content))
+		if charCount == 0 {
+			return 0
+		}
+		return (charCount + c.CharactersPerWord - 1) / c.CharactersPerWord
+	case Characters:
+		count := 0
+		for _, r := range strings.TrimSpace(content) {
+			if r == '\n' || r == '\r' || c.WithoutSpaces && unicode.IsSpace(r) {
+				continue
+			}
+			count++
+		}
+		return count
+	case UnicodeWords:
+		return CountUnicodeWords(content)
+	case CJK:
+		return CountCJK(content)
+	case Auto:
+		if IsMostlyCJK(content) {
+			return CountCJK(content)
+		}
+		return referenceCount(c.Fallback, content)
+	}
+	panic("no reference for " + c.Name())
+}
+
+func TestTallyMatchesReference(t *testing.T) {
+	samples := []string{
+		"",
+		"   ",
+		"  The quick brown fox — jumps over the “lazy” dog.  \n\n",
+		"Café naïve résumé, don't stop at 3,000.50 https://example.com/x",
+		"吾輩は猫である。名前はまだ無い。 Hello world\n",
+		"\tmixed 漢字 and kana かな\r\n",
+		"\u3000全角の空白で囲まれた文\u3000",
+		"\u00a0non-breaking\u00a0spaces\u00a0",
+		"emoji 👩‍👩‍👧 and combining e\u0301 marks",
+		"caf\xe9 with a stray byte\xff",
+		"cut off mid-rune \xe6\xbc",
+		strings.Repeat("antidisestablishmentarianism ", 50),
+		// Runs without whitespace longer than any buffer a tally might keep
+		strings.Repeat("a", 100*1024),
+		"https://example.com/" + strings.Repeat("path/", 20*1024) + " and more",
+		strings.Repeat("well-", 20*1024) + "known",
+		strings.Repeat("漢字", 20*1024),
+	}
+
+	counters := []Counter{Heuristic{CharactersPerWord: 4}}
+	for _, name := range Names() {
+		c, _ := Lookup(name)
+		counters = append(counters, c)
+	}
+
+	for _, c := range counters {
+		for _, sample := range samples {
+			want := referenceCount(c, sample)
+
+			if got := c.Count(sample); got != want {
+				t.Errorf("%s Count(%q) = %d, want %d", c.Name(), sample, got, want)
+			}
+
+			// Chunks of these sizes split words and multi-byte runes at
+			// every offset; a size of 1 splits every rune
+			for _, size := range []int{1, 2, 3, 5, 7, 64} {
+				tally := c.NewTally()
+				for i := 0; i < len(sample); i += size {
+					tally.Write([]byte(sample[i:min(i+size, len(sample))]))
+				}
+				if got := tally.Count(); got != want {
+					t.Errorf("%s tally on %q in chunks of %d = %d, want %d", c.Name(), sample, size, got, want)
+				}
+			}
+		}
+	}
+}
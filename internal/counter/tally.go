@@ -0,0 +1,155 @@
+package counter
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tally accumulates a count over text written to it in arbitrary chunks, so
+// content can be streamed through a Counter without holding it in memory.
+// Writing the same text in any split produces the same count as Counter.Count
+// on the whole text. Write never returns an error.
+type Tally interface {
+	Write(p []byte) (int, error)
+	WriteString(s string) (int, error)
+	// Count returns the count for everything written so far
+	Count() int
+}
+
+// countString counts content by writing it to a fresh tally
+func countString(c Counter, content string) int {
+	t := c.NewTally()
+	t.WriteString(content)
+	return t.Count()
+}
+
+// runeDecoder decodes UTF-8 from chunks that may split multi-byte sequences
+type runeDecoder struct {
+	partial []byte
+}
+
+// decode passes each complete rune in p, together with its encoded bytes, to emit
+func (d *runeDecoder) decode(p []byte, emit func(r rune, b []byte)) {
+	if len(d.partial) > 0 {
+		p = append(d.partial, p...)
+		d.partial = nil
+	}
+
+	for len(p) > 0 {
+		if !utf8.FullRune(p) {
+			d.partial = append([]byte(nil), p...)
+			return
+		}
+		r, n := utf8.DecodeRune(p)
+		emit(r, p[:n])
+		p = p[n:]
+	}
+}
+
+// trimmedTally counts the units in the text with leading and trailing
+// whitespace removed, mirroring strings.TrimSpace on the whole stream.
+// units reports how many units a rune contributes.
+type trimmedTally struct {
+	decoder runeDecoder
+	units   func(r rune, size int) int
+	started bool
+	count   int // Units up to and including the last non-space rune
+	pending int // Units in the whitespace after the last non-space rune
+	finish  func(count int) int
+}
+
+func (t *trimmedTally) Write(p []byte) (int, error) {
+	t.decoder.decode(p, func(r rune, b []byte) {
+		n := t.units(r, len(b))
+		if unicode.IsSpace(r) {
+			if t.started {
+				t.pending += n
+			}
+			return
+		}
+		t.started = true
+		t.count += t.pending + n
+		t.pending = 0
+	})
+	return len(p), nil
+}
+
+func (t *trimmedTally) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+func (t *trimmedTally) Count() int {
+	count := t.count
+	if len(t.decoder.partial) > 0 {
+		// An incomplete sequence at the end decodes as invalid, non-space bytes
+		count += t.pending
+		for range t.decoder.partial {
+			count += t.units(utf8.RuneError, 1)
+		}
+	}
+	return t.finish(count)
+}
+
+// wordTally counts the words of each whitespace-free field as it streams
+// past, holding no more of it than a fieldCounter does
+type wordTally struct {
+	decoder runeDecoder
+	field   fieldCounter
+	count   int
+}
+
+func (t *wordTally) Write(p []byte) (int, error) {
+	t.decoder.decode(p, func(r rune, b []byte) {
+		if unicode.IsSpace(r) {
+			t.count += t.field.finish()
+			return
+		}
+		t.field.add(r)
+	})
+	return len(p), nil
+}
+
+func (t *wordTally) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+func (t *wordTally) Count() int {
+	// An incomplete sequence at the end decodes as invalid bytes, finishing
+	// a copy of the field so more can still be written
+	field := t.field
+	for range t.decoder.partial {
+		field.add(utf8.RuneError)
+	}
+	return t.count + field.finish()
+}
+
+// autoTally runs the CJK and fallback tallies side by side and picks one
+// once the script of the whole text is known
+type autoTally struct {
+	decoder  runeDecoder
+	cjkRunes int
+	cjk      Tally
+	fallback Tally
+}
+
+func (t *autoTally) Write(p []byte) (int, error) {
+	t.decoder.decode(p, func(r rune, b []byte) {
+		if isCJKRune(r) {
+			t.cjkRunes++
+		}
+	})
+	t.cjk.Write(p)
+	t.fallback.Write(p)
+	return len(p), nil
+}
+
+func (t *autoTally) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+func (t *autoTally) Count() int {
+	if cjk := t.cjk.Count(); t.cjkRunes > 0 && t.cjkRunes*2 >= cjk {
+		return cjk
+	}
+	return t.fallback.Count()
+}
@@ -0,0 +1,202 @@
+package counter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CountUnicodeWords counts words by Unicode word boundaries rather than by
+// byte length. A word is a run of letters, marks and digits. Hyphens and
+// apostrophes between letters join compound words and contractions
+// ("well-known", "don't"), separators between digits keep numbers whole
+// ("3,000.50", "10:30") and URLs or e-mail addresses count as one word.
+// Dashes, slashes and other punctuation separate words.
+func CountUnicodeWords(content string) int {
+	count := 0
+	for _, field := range strings.FieldsFunc(content, unicode.IsSpace) {
+		count += countFieldWords(field, false)
+	}
+	return count
+}
+
+// CountCJK counts text in scripts that are not delimited by spaces. Every Han
+// ideograph, hiragana or katakana character and Hangul syllable counts as one
+// unit, as is customary for Chinese, Japanese and Korean manuscripts, while
+// embedded Latin text is counted as words like CountUnicodeWords does.
+func CountCJK(content string) int {
+	count := 0
+	for _, field := range strings.FieldsFunc(content, unicode.IsSpace) {
+		count += countFieldWords(field, true)
+	}
+	return count
+}
+
+// IsMostlyCJK reports whether at least half of the units CountCJK would
+// count in content are CJK characters
+func IsMostlyCJK(content string) bool {
+	cjk := 0
+	for _, r := range content {
+		if isCJKRune(r) {
+			cjk++
+		}
+	}
+	return cjk > 0 && cjk*2 >= CountCJK(content)
+}
+
+// countFieldWords counts the words in a single whitespace-free field. When
+// cjk is set, CJK characters count as one unit each.
+func countFieldWords(field string, cjk bool) int {
+	f := fieldCounter{cjk: cjk}
+	for _, r := range field {
+		f.add(r)
+	}
+	return f.finish()
+}
+
+// fieldCounter counts the words in a whitespace-free field fed to it one rune
+// at a time. It keeps no more than the state it needs, so a field of any
+// length can be counted as it streams past.
+type fieldCounter struct {
+	cjk     bool
+	count   int
+	inWord  bool
+	prev    rune
+	joiner  rune // Punctuation held back until the next rune shows whether it joins a word
+	holding bool
+
+	// What the field has shown so far of looking like a URL or an e-mail address
+	runes     int
+	prefix    [len("mailto:")]rune // The first runes, lowercased
+	last      [2]rune              // The two runes before the current one
+	scheme    bool                 // Contains ://
+	at        int                  // 1 after an @ that isn't the first rune, -1 after one that is
+	dotAfter  bool                 // A . at or after the first @
+	endsInDot bool
+	wordRunes bool
+	cjkRunes  bool
+}
+
+// add counts the next rune of the field
+func (f *fieldCounter) add(r rune) {
+	f.track(r)
+
+	if f.holding {
+		// The punctuation held back keeps the word open only if r continues it
+		if !joinsWord(f.prev, f.joiner, r) {
+			f.inWord = false
+		}
+		f.prev, f.holding = f.joiner, false
+	}
+
+	switch {
+	case f.cjk && isCJKRune(r):
+		f.count++
+		f.inWord = false
+	case f.cjk && unicode.IsMark(r) && isCJKRune(f.prev):
+		// Combining marks such as dakuten belong to the preceding kana
+		return
+	case isWordRune(r):
+		if !f.inWord {
+			f.count++
+			f.inWord = true
+		}
+	case f.inWord && isJoiner(r):
+		f.joiner, f.holding = r, true
+		return
+	default:
+		f.inWord = false
+	}
+	f.prev = r
+}
+
+// track records what r tells about whether the field is a URL
+func (f *fieldCounter) track(r rune) {
+	lower := unicode.ToLower(r)
+	if f.runes < len(f.prefix) {
+		f.prefix[f.runes] = lower
+	}
+	if f.last == [2]rune{':', '/'} && lower == '/' {
+		f.scheme = true
+	}
+	switch {
+	case lower == '@' && f.at == 0 && f.runes == 0:
+		f.at = -1
+	case lower == '@' && f.at == 0:
+		f.at = 1
+	}
+	if lower == '.' && f.at != 0 {
+		f.dotAfter = true
+	}
+	f.endsInDot = lower == '.'
+	f.wordRunes = f.wordRunes || isWordRune(r)
+	f.cjkRunes = f.cjkRunes || isCJKRune(r)
+	f.last = [2]rune{f.last[1], lower}
+	f.runes++
+}
+
+// isURL reports whether the field looks like a URL or an e-mail address
+func (f *fieldCounter) isURL() bool {
+	prefix := string(f.prefix[:min(f.runes, len(f.prefix))])
+	if f.scheme || strings.HasPrefix(prefix, "www.") || strings.HasPrefix(prefix, "mailto:") {
+		return true
+	}
+	return f.at > 0 && f.dotAfter && !f.endsInDot
+}
+
+// finish returns the words in the field and resets the counter for the next one
+func (f *fieldCounter) finish() int {
+	count := f.count
+	if f.isURL() && !(f.cjk && f.cjkRunes) {
+		// A URL counts as one word if it has any letters or digits
+		count = 0
+		if f.wordRunes {
+			count = 1
+		}
+	}
+	*f = fieldCounter{cjk: f.cjk}
+	return count
+}
+
+// isWordRune reports whether r can be part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// isCJKRune reports whether r is a Han ideograph, kana or Hangul syllable,
+// including the iteration and prolonged sound marks written with them
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r == '々' || r == '〆' || r == 'ー' || r == 'ｰ'
+}
+
+// isJoiner reports whether the punctuation r can join the runes either side
+// of it into one word
+func isJoiner(r rune) bool {
+	switch r {
+	case '-', '‐', '‑', '\'', '’', '.', ',', ':':
+		return true
+	}
+	return false
+}
+
+// joinsWord reports whether the punctuation r, found between prev and next,
+// continues a word rather than ending it
+func joinsWord(prev, r, next rune) bool {
+	if !isWordRune(next) {
+		return false
+	}
+
+	switch r {
+	case '-', '‐', '‑':
+		// Hyphenated compounds: well-known, twenty-one
+		return true
+	case '\'', '’', '.':
+		// Contractions and abbreviations: don't, O'Brien, e.g
+		return unicode.IsLetter(prev) && unicode.IsLetter(next) || unicode.IsDigit(prev) && unicode.IsDigit(next)
+	case ',', ':':
+		// Numbers: 3,000 and 10:30
+		return unicode.IsDigit(prev) && unicode.IsDigit(next)
+	}
+
+	return false
+}
@@ -0,0 +1,239 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the file at the top of a project that lists the paths
+// left out of its count, in gitignore syntax
+const FileName = ".verkountignore"
+
+// globPattern is one compiled line of an ignore file or exclude list
+type globPattern struct {
+	re      *regexp.Regexp
+	negate  bool // A ! pattern that brings a path back in
+	dirOnly bool // A pattern ending in / matches only directories
+}
+
+// Rules decides which paths of a project are counted, from the project's
+// .verkountignore file and the include and exclude globs of its .verkount
+// file. The zero value and nil ignore nothing.
+type Rules struct {
+	exclude []globPattern // Gitignore patterns, the last match wins
+	include []globPattern // When set, only files matching one of these, or in a folder that does, are counted
+}
+
+// Load reads the .verkountignore file of the project at folderPath, if it
+// has one, and adds the exclude and include globs. Globs use the same syntax
+// as the ignore file.
+func Load(folderPath string, include, exclude []string) (*Rules, error) {
+	rules := &Rules{}
+
+	ignorePath := filepath.Join(folderPath, FileName)
+	file, err := os.Open(ignorePath)
+	switch {
+	case err == nil:
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			if err := rules.addExclude(scanner.Text()); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", ignorePath, line, err)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	for _, glob := range exclude {
+		if err := rules.addExclude(glob); err != nil {
+			return nil, fmt.Errorf("exclude %q: %v", glob, err)
+		}
+	}
+	for _, glob := range include {
+		pattern, ok, err := compilePattern(glob)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %v", glob, err)
+		}
+		if ok {
+			rules.include = append(rules.include, pattern)
+		}
+	}
+
+	return rules, nil
+}
+
+// addExclude adds a line of gitignore syntax to the rules
+func (r *Rules) addExclude(line string) error {
+	pattern, ok, err := compilePattern(line)
+	if ok {
+		r.exclude = append(r.exclude, pattern)
+	}
+	return err
+}
+
+// Ignored reports whether the slash-separated path, relative to the project,
+// is left out of the count. A path inside an ignored directory is ignored
+// too, and include globs only ever leave out files.
+func (r *Rules) Ignored(relPath string, isDir bool) bool {
+	if r == nil || relPath == "." || relPath == "" {
+		return false
+	}
+
+	// As in git, nothing inside an ignored directory can be brought back
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.excluded(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	if r.excluded(relPath, isDir) {
+		return true
+	}
+
+	if isDir || len(r.include) == 0 {
+		return false
+	}
+
+	return !matchesFileOrFolder(r.include, relPath)
+}
+
+// Globs are globs in the syntax of the ignore file, such as those that sort
+// a project's files into categories
+type Globs []globPattern
+
+// CompileGlobs compiles globs, leaving out blank ones
+func CompileGlobs(globs []string) (Globs, error) {
+	var compiled Globs
+	for _, glob := range globs {
+		pattern, ok, err := compilePattern(glob)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			compiled = append(compiled, pattern)
+		}
+	}
+	return compiled, nil
+}
+
+// Match reports whether one of the globs matches the file at the
+// slash-separated relPath or a folder above it
+func (g Globs) Match(relPath string) bool {
+	return matchesFileOrFolder(g, relPath)
+}
+
+// matchesFileOrFolder reports whether one of patterns matches the file at
+// relPath or a folder above it
+func matchesFileOrFolder(patterns []globPattern, relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	for _, pattern := range patterns {
+		if !pattern.dirOnly && pattern.re.MatchString(relPath) {
+			return true
+		}
+		for i := 1; i < len(parts); i++ {
+			if pattern.re.MatchString(strings.Join(parts[:i], "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excluded applies the exclude patterns to a single path
+func (r *Rules) excluded(relPath string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range r.exclude {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.re.MatchString(relPath) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// compilePattern turns a line of gitignore syntax into a pattern over
+// slash-separated paths relative to the project. Blank lines and comments
+// report false.
+func compilePattern(line string) (globPattern, bool, error) {
+	var pattern globPattern
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern, false, nil
+	}
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern, false, nil
+	}
+
+	// A pattern with a slash before its end is anchored to the project;
+	// without one it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			re.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return pattern, false, fmt.Errorf("invalid pattern %q", line)
+	}
+	pattern.re = compiled
+	return pattern, true, nil
+}
@@ -0,0 +1,92 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	content := "# Research lives elsewhere\nresearch/\n/notes\n*.bak.md\n!keep.bak.md\ndrafts/**/old-*\nscene?.md\n[Tt]odo.md\n"
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create ignore file: %v", err)
+	}
+
+	rules, err := Load(tempDir, nil, []string{"archive"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"chapter.md", false, false},
+		{"research", true, true},
+		{"research", false, false},
+		{"part1/research/facts.md", false, true},
+		{"notes", true, true},
+		{"part1/notes", true, false},
+		{"a.bak.md", false, true},
+		{"part1/b.bak.md", false, true},
+		{"keep.bak.md", false, false},
+		{"drafts/old-1.md", false, true},
+		{"drafts/2020/old-1.md", false, true},
+		{"drafts/new-1.md", false, false},
+		{"scene1.md", false, true},
+		{"scene10.md", false, false},
+		{"Todo.md", false, true},
+		{"archive/2020/a.md", false, true},
+		{".", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	included, err := Load(t.TempDir(), []string{"chapters/**", "*.txt"}, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"chapters/01.md":   false,
+		"chapters/a/02.md": false,
+		"notes.txt":        false,
+		"notes.md":         true,
+		"research":         false, // Folders are still walked
+		"chapters":         false,
+	} {
+		if got := included.Ignored(path, path == "research" || path == "chapters"); got != want {
+			t.Errorf("with include globs, Ignored(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	var none *Rules
+	if none.Ignored("anything.md", false) {
+		t.Errorf("nil rules ignored a file")
+	}
+}
+
+func TestGlobsMatch(t *testing.T) {
+	globs, err := CompileGlobs([]string{"notes/", "", "*.bib.md", "drafts/**/old-*"})
+	if err != nil {
+		t.Fatalf("CompileGlobs failed: %v", err)
+	}
+
+	for path, want := range map[string]bool{
+		"notes/ideas.md":       true,
+		"part1/notes/a.md":     true,
+		"sources.bib.md":       true,
+		"drafts/2020/old-1.md": true,
+		"chapter.md":           false,
+		"drafts/new-1.md":      false,
+	} {
+		if got := globs.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileDayStats holds a project's per-file word counts for one day
+type FileDayStats struct {
+	Name     string         `yaml:"name,omitempty"` // Display name of a project recorded under its ID
+	Files    map[string]int `yaml:"files"`
+	Total    int            `yaml:"total"`
+	Chapters []ChapterCount `yaml:"chapters,omitempty"` // Chapters in reading order, when chapter counting is enabled
+}
+
+// ChapterCount is the word count of one chapter or section
+type ChapterCount struct {
+	Label string `yaml:"label"`
+	Words int    `yaml:"words"`
+}
+
+type FileStatsFile map[string]FileDayStats
+
+// WriteFileStats records a project's per-file and per-chapter word counts in
+// the file history at files/<project>_files.yaml in the XDG data directory.
+// project is the key of the project's history and name its display name; a
+// history kept under legacyKey, before the project had an ID or shared its
+// name, is moved to the key. A slash in a qualified key, e.g. Fantasy/Book-1,
+// is an underscore in the file name.
+func WriteFileStats(project, name, legacyKey string, files map[string]int, chapters []ChapterCount) error {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return err
+	}
+
+	filesDir := filepath.Join(dataDir, "files")
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		return err
+	}
+
+	fileName := strings.ReplaceAll(project, "/", "_")
+	statsFilePath := filepath.Join(filesDir, fileName+"_files.yaml")
+	if legacyKey != "" && legacyKey != project {
+		legacyPath := filepath.Join(filesDir, strings.ReplaceAll(legacyKey, "/", "_")+"_files.yaml")
+		if _, err := os.Stat(statsFilePath); os.IsNotExist(err) {
+			if _, err := os.Stat(legacyPath); err == nil {
+				if err := os.Rename(legacyPath, statsFilePath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if name == fileName {
+		name = ""
+	}
+
+	existingStats := make(FileStatsFile)
+	data, err := os.ReadFile(statsFilePath)
+	if err == nil {
+		if err := yaml.Unmarshal(data, &existingStats); err != nil {
+			fmt.Printf("Warning: Could not parse existing file history for %s: %v\n", project, err)
+			existingStats = make(FileStatsFile)
+		}
+	}
+
+	total := 0
+	for _, count := range files {
+		total += count
+	}
+
+	// Skip the update if no file or chapter changed since the most recent entry
+	var mostRecentDate string
+	for date := range existingStats {
+		if date > mostRecentDate {
+			mostRecentDate = date
+		}
+	}
+	if mostRecentDate != "" {
+		recent := existingStats[mostRecentDate]
+		if statsAreEqual(recent.Files, files) && chaptersAreEqual(recent.Chapters, chapters) && recent.Name == name {
+			return nil
+		}
+	}
+
+	existingStats[time.Now().Format("2006-01-02")] = FileDayStats{
+		Name:     name,
+		Files:    files,
+		Total:    total,
+		Chapters: chapters,
+	}
+
+	updatedData, err := yaml.Marshal(existingStats)
+	if err != nil {
+		return fmt.Errorf("error marshaling file history for %s: %v", project, err)
+	}
+
+	if err := os.WriteFile(statsFilePath, updatedData, 0644); err != nil {
+		return fmt.Errorf("error writing file history for %s: %v", project, err)
+	}
+
+	return nil
+}
+
+// chaptersAreEqual compares two chapter lists in order
+func chaptersAreEqual(chapters1, chapters2 []ChapterCount) bool {
+	if len(chapters1) != len(chapters2) {
+		return false
+	}
+
+	for i := range chapters1 {
+		if chapters1[i] != chapters2[i] {
+			return false
+		}
+	}
+
+	return true
+}
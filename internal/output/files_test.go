@@ -0,0 +1,108 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteFileStatsAdoptsLegacyHistory(t *testing.T) {
+	legacy := FileStatsFile{"2026-01-01": {Files: map[string]int{"one.md": 100}, Total: 100}}
+
+	tests := []struct {
+		name      string
+		project   string
+		display   string
+		legacyKey string
+		existing  bool   // Whether the project already has a history under its key
+		adopted   bool   // Whether the legacy history becomes the project's
+		file      string // File the project's history is written to
+	}{
+		{
+			name:      "Qualified name recorded under an ID",
+			project:   "a1",
+			display:   "Fantasy/Book-1",
+			legacyKey: "Book-1",
+			adopted:   true,
+			file:      "a1_files.yaml",
+		},
+		{
+			name:      "Qualified name without an ID",
+			project:   "Fantasy/Book-1",
+			display:   "Fantasy/Book-1",
+			legacyKey: "Book-1",
+			adopted:   true,
+			file:      "Fantasy_Book-1_files.yaml",
+		},
+		{
+			name:    "Name shared by several projects",
+			project: "a1",
+			display: "Fantasy/Book-1",
+			file:    "a1_files.yaml",
+		},
+		{
+			name:      "History under the key",
+			project:   "a1",
+			display:   "Book-1",
+			legacyKey: "Book-1",
+			existing:  true,
+			file:      "a1_files.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			dataDir, err := getDataDir()
+			if err != nil {
+				t.Fatalf("getDataDir failed: %v", err)
+			}
+			filesDir := filepath.Join(dataDir, "files")
+			if err := os.MkdirAll(filesDir, 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+
+			legacyPath := filepath.Join(filesDir, "Book-1_files.yaml")
+			data, _ := yaml.Marshal(legacy)
+			if err := os.WriteFile(legacyPath, data, 0644); err != nil {
+				t.Fatalf("Failed to write history: %v", err)
+			}
+			if tt.existing {
+				data, _ := yaml.Marshal(FileStatsFile{"2026-01-02": {Files: map[string]int{"two.md": 7}, Total: 7}})
+				if err := os.WriteFile(filepath.Join(filesDir, tt.file), data, 0644); err != nil {
+					t.Fatalf("Failed to write history: %v", err)
+				}
+			}
+
+			if err := WriteFileStats(tt.project, tt.display, tt.legacyKey, map[string]int{"one.md": 120}, nil); err != nil {
+				t.Fatalf("WriteFileStats failed: %v", err)
+			}
+
+			data, err = os.ReadFile(filepath.Join(filesDir, tt.file))
+			if err != nil {
+				t.Fatalf("Failed to read history: %v", err)
+			}
+			var history FileStatsFile
+			if err := yaml.Unmarshal(data, &history); err != nil {
+				t.Fatalf("Failed to parse history: %v", err)
+			}
+
+			_, hasLegacy := history["2026-01-01"]
+			if hasLegacy != tt.adopted {
+				t.Errorf("history has the legacy entry: %v, want %v", hasLegacy, tt.adopted)
+			}
+			if _, err := os.Stat(legacyPath); os.IsNotExist(err) != tt.adopted {
+				t.Errorf("legacy history moved: %v, want %v", os.IsNotExist(err), tt.adopted)
+			}
+			entries := 1
+			if tt.adopted || tt.existing {
+				entries = 2
+			}
+			if len(history) != entries {
+				t.Errorf("history has %d entries, want %d: %+v", len(history), entries, history)
+			}
+		})
+	}
+}
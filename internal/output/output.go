@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/bwilson/verkounter/internal/counter"
@@ -11,9 +12,48 @@ import (
 )
 
 type DayStats struct {
-	Projects map[string]int `yaml:"projects"`
-	Total    int            `yaml:"total"`
-	Delta    int            `yaml:"delta,omitempty"` // Words written compared to previous entry
+	Projects map[string]int    `yaml:"projects"`
+	Total    int               `yaml:"total"`
+	Delta    int               `yaml:"delta,omitempty"`   // Words written compared to previous entry
+	Methods  map[string]string `yaml:"methods,omitempty"` // Counting strategy used for each project
+	Names    map[string]string `yaml:"names,omitempty"`   // Display name of each project recorded under its ID
+
+	Extraction map[string]string `yaml:"extraction,omitempty"` // Markdown extraction mode of each project, raw when missing
+
+	Categories     map[string]int `yaml:"categories,omitempty"`      // Words per category, e.g. manuscript or notes, across all projects
+	CategoryDeltas map[string]int `yaml:"category_deltas,omitempty"` // Words written per category compared to previous entry
+
+	Screenplays map[string]ScreenplayStats `yaml:"screenplays,omitempty"` // Metrics of projects with Fountain screenplays
+	Breakdowns  map[string]BreakdownStats  `yaml:"breakdowns,omitempty"`  // Words per status, POV and tag of projects whose files set them
+}
+
+// ScreenplayStats holds the metrics of a project's screenplays
+type ScreenplayStats struct {
+	Scenes        int     `yaml:"scenes"`
+	Pages         float64 `yaml:"pages"`
+	DialogueWords int     `yaml:"dialogue_words"`
+	ActionWords   int     `yaml:"action_words"`
+}
+
+// BreakdownStats holds a project's words per value of the status, pov and
+// tags frontmatter keys
+type BreakdownStats struct {
+	Status map[string]int `yaml:"status,omitempty"`
+	POV    map[string]int `yaml:"pov,omitempty"`
+	Tags   map[string]int `yaml:"tags,omitempty"`
+}
+
+// ProjectResult is the outcome of counting a single project
+type ProjectResult struct {
+	Words      int
+	Name       string           // Sanitized display name of the project
+	LegacyKey  string           // Key of the project's history before it had an ID or shared its name
+	Series     string           // Series the project belongs to, empty for none
+	Method     string           // Name of the counting strategy that produced Words
+	Extraction string           // Markdown extraction mode that produced Words, see processor.MarkdownOptions.Mode
+	Categories map[string]int   // Words per category of the project
+	Screenplay *ScreenplayStats // Screenplay metrics, nil for projects without screenplays
+	Breakdown  *BreakdownStats  // Words per status, POV and tag, nil when no file sets them
 }
 
 type StatsFile map[string]DayStats
@@ -81,7 +121,7 @@ func migrateOldStats() error {
 	return nil
 }
 
-func WriteStats(results map[string]int, outputPath string) error {
+func WriteStats(results map[string]ProjectResult, outputPath string) error {
 	// First, try to migrate old stats if they exist
 	if err := migrateOldStats(); err != nil {
 		fmt.Printf("Warning: Could not migrate old stats: %v\n", err)
@@ -106,31 +146,68 @@ func WriteStats(results map[string]int, outputPath string) error {
 
 	dateKey := time.Now().Format("2006-01-02")
 
+	projects, methods := splitResults(results)
+	screenplays := screenplayResults(results)
+	breakdowns := breakdownResults(results)
+	categories := categoryTotals(results)
+	names := projectNames(results)
+	extraction := extractionModes(results)
 	total := 0
-	for _, count := range results {
+	for _, count := range projects {
 		total += count
 	}
 
+	migrated := splitCollidingProjects(existingStats, results, filepath.Join(dataDir, "series"))
+	if adoptProjectKeys(existingStats, results) {
+		migrated = true
+	}
+
 	// Check if the most recent stats are identical to current results
 	recentStats, _, found := getMostRecentStats(existingStats)
 	if found {
-		if statsAreEqual(recentStats.Projects, results) && recentStats.Total == total {
+		if statsAreEqual(recentStats.Projects, projects) && methodsAreEqual(recentStats.Methods, methods) &&
+			namesAreEqual(recentStats.Names, names) && extractionsAreEqual(recentStats.Extraction, extraction) &&
+			screenplaysAreEqual(recentStats.Screenplays, screenplays) && breakdownsAreEqual(recentStats.Breakdowns, breakdowns) &&
+			statsAreEqual(recentStats.Categories, categories) && recentStats.Total == total {
 			fmt.Println("No changes in word counts - skipping update of main stats file")
+			if migrated {
+				return writeStatsFile(statsFilePath, existingStats)
+			}
 			return nil
 		}
 	}
 
 	// Calculate delta from most recent entry
 	delta := 0
+	deltas := categoryDeltas(recentStats, found, categories)
 	if found {
 		delta = total - recentStats.Total
 		// First entry gets delta of 0 (it's the baseline)
+
+		rebased := rebasedProjects(recentStats, results)
+		for _, key := range rebased {
+			result := results[key]
+			fmt.Printf("Note: %s is now counted with %s Markdown extraction instead of %s, so today's change in its count is not recorded as words written\n",
+				result.Name, result.Extraction, extractionMode(recentStats, key))
+		}
+		delta = rebasedDelta(delta, recentStats, projects, rebased)
+		if len(rebased) > 0 {
+			// The categories of the projects before the change are unknown
+			deltas = nil
+		}
 	}
 
 	existingStats[dateKey] = DayStats{
-		Projects: results,
-		Total:    total,
-		Delta:    delta,
+		Projects:       projects,
+		Total:          total,
+		Delta:          delta,
+		Methods:        methods,
+		Names:          names,
+		Extraction:     extraction,
+		Categories:     categories,
+		CategoryDeltas: deltas,
+		Screenplays:    screenplays,
+		Breakdowns:     breakdowns,
 	}
 
 	updatedData, err := yaml.Marshal(existingStats)
@@ -178,7 +255,7 @@ func migrateSeriesStats(seriesName string, documentsPath string) error {
 }
 
 // WriteSeriesStats writes stats for each series to a YAML file in the XDG data directory
-func WriteSeriesStats(seriesResults map[string]map[string]int, scanPath string) error {
+func WriteSeriesStats(seriesResults map[string]map[string]ProjectResult, scanPath string) error {
 	dateKey := time.Now().Format("2006-01-02")
 
 	dataDir, err := getDataDir()
@@ -220,34 +297,64 @@ func WriteSeriesStats(seriesResults map[string]map[string]int, scanPath string)
 
 		// Calculate total for this series
 		total := 0
-		sanitizedProjects := make(map[string]int)
-		for projectName, count := range projects {
+		sanitizedResults := make(map[string]ProjectResult)
+		for projectName, result := range projects {
 			sanitizedName := counter.SanitizeFolderName(projectName)
-			sanitizedProjects[sanitizedName] = count
-			total += count
+			sanitizedResults[sanitizedName] = result
+			total += result.Words
 		}
+		sanitizedProjects, methods := splitResults(sanitizedResults)
+		screenplays := screenplayResults(sanitizedResults)
+		breakdowns := breakdownResults(sanitizedResults)
+		categories := categoryTotals(sanitizedResults)
+		names := projectNames(sanitizedResults)
+		extraction := extractionModes(sanitizedResults)
+
+		migrated := adoptProjectKeys(existingStats, sanitizedResults)
 
 		// Check if the most recent stats are identical to current results
 		recentStats, _, found := getMostRecentStats(existingStats)
 		if found {
-			if statsAreEqual(recentStats.Projects, sanitizedProjects) && recentStats.Total == total {
+			if statsAreEqual(recentStats.Projects, sanitizedProjects) && methodsAreEqual(recentStats.Methods, methods) &&
+				namesAreEqual(recentStats.Names, names) && extractionsAreEqual(recentStats.Extraction, extraction) &&
+				screenplaysAreEqual(recentStats.Screenplays, screenplays) && breakdownsAreEqual(recentStats.Breakdowns, breakdowns) &&
+				statsAreEqual(recentStats.Categories, categories) && recentStats.Total == total {
 				fmt.Printf("No changes in word counts for series %s - skipping update\n", seriesName)
+				if migrated {
+					if err := writeStatsFile(statsFilePath, existingStats); err != nil {
+						return fmt.Errorf("error writing series stats for %s: %v", seriesName, err)
+					}
+				}
 				continue
 			}
 		}
 
 		// Calculate delta from most recent entry
 		delta := 0
+		deltas := categoryDeltas(recentStats, found, categories)
 		if found {
 			delta = total - recentStats.Total
 			// First entry gets delta of 0 (it's the baseline)
+
+			rebased := rebasedProjects(recentStats, sanitizedResults)
+			delta = rebasedDelta(delta, recentStats, sanitizedProjects, rebased)
+			if len(rebased) > 0 {
+				deltas = nil
+			}
 		}
 
 		// Update stats for today
 		existingStats[dateKey] = DayStats{
-			Projects: sanitizedProjects,
-			Total:    total,
-			Delta:    delta,
+			Projects:       sanitizedProjects,
+			Total:          total,
+			Delta:          delta,
+			Methods:        methods,
+			Names:          names,
+			Extraction:     extraction,
+			Categories:     categories,
+			CategoryDeltas: deltas,
+			Screenplays:    screenplays,
+			Breakdowns:     breakdowns,
 		}
 
 		// Write updated stats
@@ -281,6 +388,475 @@ func statsAreEqual(stats1, stats2 map[string]int) bool {
 	return true
 }
 
+// splitResults separates project results into the word count and method maps stored in DayStats
+func splitResults(results map[string]ProjectResult) (map[string]int, map[string]string) {
+	projects := make(map[string]int)
+	methods := make(map[string]string)
+	for name, result := range results {
+		projects[name] = result.Words
+		methods[name] = result.Method
+	}
+	return projects, methods
+}
+
+// writeStatsFile saves a stats file
+func writeStatsFile(path string, stats StatsFile) error {
+	data, err := yaml.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// projectNames returns the display names of the projects recorded under an
+// ID rather than their name
+func projectNames(results map[string]ProjectResult) map[string]string {
+	names := make(map[string]string)
+	for key, result := range results {
+		if result.Name != "" && result.Name != key {
+			names[key] = result.Name
+		}
+	}
+	return names
+}
+
+// extractionModes returns the Markdown extraction mode of each project that
+// reports one
+func extractionModes(results map[string]ProjectResult) map[string]string {
+	modes := make(map[string]string)
+	for key, result := range results {
+		if result.Extraction != "" {
+			modes[key] = result.Extraction
+		}
+	}
+	return modes
+}
+
+// extractionMode returns the Markdown extraction mode recorded for a project
+// in day. Entries written before extraction existed counted raw Markdown.
+func extractionMode(day DayStats, key string) string {
+	if mode, ok := day.Extraction[key]; ok {
+		return mode
+	}
+	return "raw"
+}
+
+// rebasedProjects returns the projects in previous whose Markdown extraction
+// has changed since, sorted. Their new counts are baselines rather than
+// words written.
+func rebasedProjects(previous DayStats, results map[string]ProjectResult) []string {
+	var rebased []string
+	for key, result := range results {
+		if _, counted := previous.Projects[key]; !counted || result.Extraction == "" {
+			continue
+		}
+		if result.Extraction != extractionMode(previous, key) {
+			rebased = append(rebased, key)
+		}
+	}
+	sort.Strings(rebased)
+	return rebased
+}
+
+// rebasedDelta returns delta without the change in the counts of the rebased
+// projects since previous
+func rebasedDelta(delta int, previous DayStats, projects map[string]int, rebased []string) int {
+	for _, key := range rebased {
+		delta -= projects[key] - previous.Projects[key]
+	}
+	return delta
+}
+
+// extractionsAreEqual compares two maps of Markdown extraction modes
+func extractionsAreEqual(modes1, modes2 map[string]string) bool {
+	if len(modes1) != len(modes2) {
+		return false
+	}
+
+	for key, val1 := range modes1 {
+		if val2, exists := modes2[key]; !exists || val1 != val2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// namesAreEqual compares two maps of display names
+func namesAreEqual(names1, names2 map[string]string) bool {
+	if len(names1) != len(names2) {
+		return false
+	}
+
+	for key, val1 := range names1 {
+		if val2, exists := names2[key]; !exists || val1 != val2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// adoptProjectKeys moves the history a project recorded under its sanitized
+// name, before it had an ID or shared its name, to its key, so that its
+// history carries on unbroken. A name that several projects had is left to
+// splitCollidingProjects. It reports whether any entry changed.
+func adoptProjectKeys(stats StatsFile, results map[string]ProjectResult) bool {
+	legacyKeys := make(map[string]int)
+	for _, result := range results {
+		legacyKeys[result.LegacyKey]++
+	}
+
+	migrated := false
+	for key, result := range results {
+		legacy := result.LegacyKey
+		if legacy == "" || legacy == key || legacyKeys[legacy] > 1 || hasProject(stats, key) {
+			continue
+		}
+
+		for date, dayStats := range stats {
+			words, exists := dayStats.Projects[legacy]
+			if !exists {
+				continue
+			}
+			if _, isID := dayStats.Names[legacy]; isID {
+				// Already the ID of another project
+				continue
+			}
+
+			delete(dayStats.Projects, legacy)
+			dayStats.Projects[key] = words
+			moveProject(&dayStats, dayStats, legacy, key)
+			if result.Name != key {
+				if dayStats.Names == nil {
+					dayStats.Names = make(map[string]string)
+				}
+				dayStats.Names[key] = result.Name
+			}
+
+			stats[date] = dayStats
+			migrated = true
+		}
+	}
+	return migrated
+}
+
+// moveProject copies the method, screenplay and breakdown recorded for a
+// project under from in source to the project recorded under to in target,
+// and removes them from target under from
+func moveProject(target *DayStats, source DayStats, from, to string) {
+	method, hasMethod := source.Methods[from]
+	extraction, hasExtraction := source.Extraction[from]
+	screenplay, hasScreenplay := source.Screenplays[from]
+	breakdown, hasBreakdown := source.Breakdowns[from]
+
+	delete(target.Methods, from)
+	delete(target.Extraction, from)
+	delete(target.Screenplays, from)
+	delete(target.Breakdowns, from)
+
+	if hasMethod {
+		if target.Methods == nil {
+			target.Methods = make(map[string]string)
+		}
+		target.Methods[to] = method
+	}
+	if hasExtraction {
+		if target.Extraction == nil {
+			target.Extraction = make(map[string]string)
+		}
+		target.Extraction[to] = extraction
+	}
+	if hasScreenplay {
+		if target.Screenplays == nil {
+			target.Screenplays = make(map[string]ScreenplayStats)
+		}
+		target.Screenplays[to] = screenplay
+	}
+	if hasBreakdown {
+		if target.Breakdowns == nil {
+			target.Breakdowns = make(map[string]BreakdownStats)
+		}
+		target.Breakdowns[to] = breakdown
+	}
+}
+
+// splitCollidingProjects repairs the main stats file for projects that shared
+// a name. Their counts were recorded under that name, each run keeping only
+// one of them. The stats file of each project's series kept them apart, so
+// each project's count on every day is taken from there, and the totals and
+// deltas are recalculated. Entries the series stats cannot split are left
+// as they were. Category totals of the repaired entries are dropped. It
+// reports whether any entry changed.
+func splitCollidingProjects(stats StatsFile, results map[string]ProjectResult, seriesDir string) bool {
+	byLegacyKey := make(map[string][]string)
+	for key, result := range results {
+		if result.LegacyKey != "" && result.LegacyKey != key {
+			byLegacyKey[result.LegacyKey] = append(byLegacyKey[result.LegacyKey], key)
+		}
+	}
+
+	var dates []string
+	for date := range stats {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	series := make(map[string]StatsFile) // Series stats files read so far, by series
+	changed := make(map[string]bool)     // Dates whose entries were repaired
+	for legacy, keys := range byLegacyKey {
+		if len(keys) < 2 {
+			continue
+		}
+
+		// A series stats file recorded same-named projects of the series under
+		// one name as well, so only a project alone in its series can take
+		// the count recorded there under that name
+		inSeries := make(map[string]int)
+		for _, key := range keys {
+			inSeries[results[key].Series]++
+		}
+
+		repaired := false
+		for _, date := range dates {
+			dayStats := stats[date]
+			if _, exists := dayStats.Projects[legacy]; !exists {
+				continue
+			}
+			if _, isID := dayStats.Names[legacy]; isID {
+				continue
+			}
+
+			sources := make(map[string]DayStats) // Series entry each project's count is taken from
+			from := make(map[string]string)      // Name each project has in its series entry
+			for _, key := range keys {
+				result := results[key]
+				if result.Series == "" {
+					// Projects outside a series have no series stats
+					continue
+				}
+				if series[result.Series] == nil {
+					series[result.Series] = readStatsFile(filepath.Join(seriesDir, result.Series+"_stats.yaml"))
+				}
+
+				seriesStats, found := statsOnOrBefore(series[result.Series], date)
+				if !found {
+					continue
+				}
+				name := key
+				if _, exists := seriesStats.Projects[name]; !exists && inSeries[result.Series] == 1 {
+					name = legacy
+				}
+				if _, exists := seriesStats.Projects[name]; exists {
+					sources[key] = seriesStats
+					from[key] = name
+				}
+			}
+			if len(from) == 0 {
+				// Nothing tells the projects apart on this date, so its
+				// entry is left as it was
+				continue
+			}
+
+			delete(dayStats.Projects, legacy)
+			delete(dayStats.Methods, legacy)
+			delete(dayStats.Extraction, legacy)
+			delete(dayStats.Screenplays, legacy)
+			delete(dayStats.Breakdowns, legacy)
+			for key, name := range from {
+				result := results[key]
+				dayStats.Projects[key] = sources[key].Projects[name]
+				moveProject(&dayStats, sources[key], name, key)
+				if result.Name != key {
+					if dayStats.Names == nil {
+						dayStats.Names = make(map[string]string)
+					}
+					dayStats.Names[key] = result.Name
+				}
+			}
+
+			stats[date] = dayStats
+			changed[date] = true
+			repaired = true
+		}
+		if repaired {
+			fmt.Printf("Separated the history of the projects named %s using their series stats\n", legacy)
+		}
+	}
+	if len(changed) == 0 {
+		return false
+	}
+
+	for i, date := range dates {
+		if !changed[date] && (i == 0 || !changed[dates[i-1]]) {
+			continue
+		}
+		dayStats := stats[date]
+		if changed[date] {
+			dayStats.Total = 0
+			for _, words := range dayStats.Projects {
+				dayStats.Total += words
+			}
+			// The categories held one project's words, with no way to tell whose
+			dayStats.Categories = nil
+			dayStats.CategoryDeltas = nil
+		}
+		if i > 0 {
+			dayStats.Delta = dayStats.Total - stats[dates[i-1]].Total
+		}
+		stats[date] = dayStats
+	}
+	return true
+}
+
+// readStatsFile reads a stats file, which is empty when it cannot be read
+func readStatsFile(path string) StatsFile {
+	stats := make(StatsFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &stats); err != nil {
+			return make(StatsFile)
+		}
+	}
+	return stats
+}
+
+// statsOnOrBefore returns the most recent entry of stats made on or before date
+func statsOnOrBefore(stats StatsFile, date string) (DayStats, bool) {
+	var latest string
+	for entryDate := range stats {
+		if entryDate <= date && entryDate > latest {
+			latest = entryDate
+		}
+	}
+	if latest == "" {
+		return DayStats{}, false
+	}
+	return stats[latest], true
+}
+
+// hasProject reports whether any entry records a project under key
+func hasProject(stats StatsFile, key string) bool {
+	for _, dayStats := range stats {
+		if _, exists := dayStats.Projects[key]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryTotals adds up the words per category of all projects
+func categoryTotals(results map[string]ProjectResult) map[string]int {
+	categories := make(map[string]int)
+	for _, result := range results {
+		for category, words := range result.Categories {
+			categories[category] += words
+		}
+	}
+	return categories
+}
+
+// categoryDeltas returns the words written per category since the previous
+// entry. There are none when it is the first entry, or the previous entry was
+// written before categories were recorded.
+func categoryDeltas(previous DayStats, found bool, categories map[string]int) map[string]int {
+	if !found || previous.Categories == nil {
+		return nil
+	}
+
+	deltas := make(map[string]int)
+	for category, words := range categories {
+		if delta := words - previous.Categories[category]; delta != 0 {
+			deltas[category] = delta
+		}
+	}
+	for category, words := range previous.Categories {
+		if _, exists := categories[category]; !exists && words != 0 {
+			deltas[category] = -words
+		}
+	}
+	return deltas
+}
+
+// screenplayResults collects the screenplay metrics of the projects that have them
+func screenplayResults(results map[string]ProjectResult) map[string]ScreenplayStats {
+	screenplays := make(map[string]ScreenplayStats)
+	for name, result := range results {
+		if result.Screenplay != nil {
+			screenplays[name] = *result.Screenplay
+		}
+	}
+	return screenplays
+}
+
+// screenplaysAreEqual compares two maps of screenplay metrics
+func screenplaysAreEqual(screenplays1, screenplays2 map[string]ScreenplayStats) bool {
+	if len(screenplays1) != len(screenplays2) {
+		return false
+	}
+
+	for key, val1 := range screenplays1 {
+		if val2, exists := screenplays2[key]; !exists || val1 != val2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// breakdownResults collects the breakdowns of the projects that have them
+func breakdownResults(results map[string]ProjectResult) map[string]BreakdownStats {
+	breakdowns := make(map[string]BreakdownStats)
+	for name, result := range results {
+		if result.Breakdown != nil {
+			breakdowns[name] = *result.Breakdown
+		}
+	}
+	return breakdowns
+}
+
+// breakdownsAreEqual compares two maps of project breakdowns
+func breakdownsAreEqual(breakdowns1, breakdowns2 map[string]BreakdownStats) bool {
+	if len(breakdowns1) != len(breakdowns2) {
+		return false
+	}
+
+	for key, val1 := range breakdowns1 {
+		val2, exists := breakdowns2[key]
+		if !exists || !statsAreEqual(val1.Status, val2.Status) || !statsAreEqual(val1.POV, val2.POV) ||
+			!statsAreEqual(val1.Tags, val2.Tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// methodsAreEqual compares two maps of counting methods. Entries written
+// before methods were recorded are treated as using the heuristic.
+func methodsAreEqual(methods1, methods2 map[string]string) bool {
+	if len(methods1) != len(methods2) {
+		return len(methods1) == 0 && allHeuristic(methods2)
+	}
+
+	for key, val1 := range methods1 {
+		if val2, exists := methods2[key]; !exists || val1 != val2 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allHeuristic reports whether every project in methods was counted with the heuristic
+func allHeuristic(methods map[string]string) bool {
+	for _, method := range methods {
+		if method != (counter.Heuristic{}).Name() {
+			return false
+		}
+	}
+	return true
+}
+
 // getMostRecentStats returns the most recent stats entry from a StatsFile
 func getMostRecentStats(stats StatsFile) (DayStats, string, bool) {
 	var mostRecentDate string
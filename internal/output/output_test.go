@@ -0,0 +1,409 @@
+package output
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAdoptProjectKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    StatsFile
+		results  map[string]ProjectResult
+		expected StatsFile
+		migrated bool
+	}{
+		{
+			name: "History under the name moves to the ID",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100, "Notes": 5}, Total: 105, Methods: map[string]string{"Book": "unicode"}},
+			},
+			results: map[string]ProjectResult{
+				"a1":    {Name: "Book", LegacyKey: "Book"},
+				"Notes": {Name: "Notes", LegacyKey: "Notes"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"a1": 100, "Notes": 5}, Total: 105, Methods: map[string]string{"a1": "unicode"}, Names: map[string]string{"a1": "Book"}},
+			},
+			migrated: true,
+		},
+		{
+			name: "History under the name moves to the qualified name",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+			results: map[string]ProjectResult{
+				"Fantasy/Book": {Name: "Fantasy/Book", LegacyKey: "Book"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Fantasy/Book": 100}, Total: 100},
+			},
+			migrated: true,
+		},
+		{
+			name: "Name shared by several projects",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+			results: map[string]ProjectResult{
+				"Fantasy/Book": {Name: "Fantasy/Book", LegacyKey: "Book", Series: "Fantasy"},
+				"SciFi/Book":   {Name: "SciFi/Book", LegacyKey: "Book", Series: "SciFi"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+		},
+		{
+			name: "Key that already has history",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+				"2026-01-02": {Projects: map[string]int{"a1": 120}, Total: 120, Delta: 20, Names: map[string]string{"a1": "Book"}},
+			},
+			results: map[string]ProjectResult{
+				"a1": {Name: "Book", LegacyKey: "Book"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+				"2026-01-02": {Projects: map[string]int{"a1": 120}, Total: 120, Delta: 20, Names: map[string]string{"a1": "Book"}},
+			},
+		},
+		{
+			name: "Name that is the ID of another project",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+			results: map[string]ProjectResult{
+				"e5": {Name: "c3", LegacyKey: "c3"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+		},
+		{
+			name: "Projects that share an ID are recorded under their names",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+			results: map[string]ProjectResult{
+				"Book":   {Name: "Book", LegacyKey: "Book"},
+				"Sequel": {Name: "Sequel", LegacyKey: "Sequel"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if migrated := adoptProjectKeys(tt.stats, tt.results); migrated != tt.migrated {
+				t.Errorf("adoptProjectKeys() = %v, want %v", migrated, tt.migrated)
+			}
+			if !reflect.DeepEqual(tt.stats, tt.expected) {
+				t.Errorf("stats = %+v, want %+v", tt.stats, tt.expected)
+			}
+
+			// A second run finds nothing left to move
+			if adoptProjectKeys(tt.stats, tt.results) {
+				t.Errorf("second adoptProjectKeys() = true, want false")
+			}
+			if !reflect.DeepEqual(tt.stats, tt.expected) {
+				t.Errorf("stats after a second run = %+v, want %+v", tt.stats, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitCollidingProjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    StatsFile
+		series   map[string]StatsFile // Series stats files, by series
+		results  map[string]ProjectResult
+		expected StatsFile
+		changed  bool
+	}{
+		{
+			name: "Same name in different series",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100, "Notes": 50}, Total: 150, Categories: map[string]int{"manuscript": 100}},
+				"2026-01-02": {Projects: map[string]int{"Book": 300, "Notes": 50}, Total: 350, Delta: 200, Methods: map[string]string{"Book": "unicode"}},
+				"2026-01-03": {Projects: map[string]int{"Notes": 60}, Total: 60, Delta: -290},
+			},
+			series: map[string]StatsFile{
+				"Fantasy": {
+					"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+					"2026-01-02": {Projects: map[string]int{"Book": 120}, Total: 120, Methods: map[string]string{"Book": "heuristic"}},
+				},
+				"SciFi": {
+					"2026-01-02": {Projects: map[string]int{"Book": 300}, Total: 300, Methods: map[string]string{"Book": "unicode"}},
+				},
+			},
+			results: map[string]ProjectResult{
+				"a1":    {Name: "Fantasy/Book", LegacyKey: "Book", Series: "Fantasy"},
+				"b2":    {Name: "SciFi/Book", LegacyKey: "Book", Series: "SciFi"},
+				"Notes": {Name: "Notes", LegacyKey: "Notes", Series: "Journal"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"a1": 100, "Notes": 50}, Total: 150, Names: map[string]string{"a1": "Fantasy/Book"}},
+				"2026-01-02": {
+					Projects: map[string]int{"a1": 120, "b2": 300, "Notes": 50},
+					Total:    470,
+					Delta:    320,
+					Methods:  map[string]string{"a1": "heuristic", "b2": "unicode"},
+					Names:    map[string]string{"a1": "Fantasy/Book", "b2": "SciFi/Book"},
+				},
+				"2026-01-03": {Projects: map[string]int{"Notes": 60}, Total: 60, Delta: -410},
+			},
+			changed: true,
+		},
+		{
+			name: "Same name in the same series",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+				"2026-01-02": {Projects: map[string]int{"Book": 130}, Total: 130, Delta: 30},
+			},
+			series: map[string]StatsFile{
+				"Fantasy": {
+					// The series file kept the projects apart only once they had IDs
+					"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+					"2026-01-02": {Projects: map[string]int{"a1": 130, "c3": 40}, Total: 170},
+				},
+			},
+			results: map[string]ProjectResult{
+				"a1": {Name: "Fantasy/Book", LegacyKey: "Book", Series: "Fantasy"},
+				"c3": {Name: "Fantasy/Drafts/Book", LegacyKey: "Book", Series: "Fantasy"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+				"2026-01-02": {
+					Projects: map[string]int{"a1": 130, "c3": 40},
+					Total:    170,
+					Delta:    70,
+					Names:    map[string]string{"a1": "Fantasy/Book", "c3": "Fantasy/Drafts/Book"},
+				},
+			},
+			changed: true,
+		},
+		{
+			name: "Same name in the same series without IDs in its stats",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+			series: map[string]StatsFile{
+				"Fantasy": {"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100}},
+			},
+			results: map[string]ProjectResult{
+				"Fantasy/Book":        {Name: "Fantasy/Book", LegacyKey: "Book", Series: "Fantasy"},
+				"Fantasy/Drafts/Book": {Name: "Fantasy/Drafts/Book", LegacyKey: "Book", Series: "Fantasy"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+		},
+		{
+			name: "Project at the root of the scan",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+			series: map[string]StatsFile{
+				"Fantasy": {"2026-01-01": {Projects: map[string]int{"Book": 80}, Total: 80}},
+			},
+			// The project outside a series keeps the name, and the history under it
+			results: map[string]ProjectResult{
+				"Book":         {Name: "Book", LegacyKey: "Book"},
+				"Fantasy/Book": {Name: "Fantasy/Book", LegacyKey: "Book", Series: "Fantasy"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100},
+			},
+		},
+		{
+			name: "Names that are IDs",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+			series: map[string]StatsFile{
+				"Fantasy": {"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100}},
+			},
+			results: map[string]ProjectResult{
+				"Fantasy/c3": {Name: "Fantasy/c3", LegacyKey: "c3", Series: "Fantasy"},
+				"SciFi/c3":   {Name: "SciFi/c3", LegacyKey: "c3", Series: "SciFi"},
+			},
+			expected: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"c3": 100}, Total: 100, Names: map[string]string{"c3": "Book"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seriesDir := t.TempDir()
+			for series, stats := range tt.series {
+				if err := writeStatsFile(filepath.Join(seriesDir, series+"_stats.yaml"), stats); err != nil {
+					t.Fatalf("Failed to write series stats: %v", err)
+				}
+			}
+
+			if changed := splitCollidingProjects(tt.stats, tt.results, seriesDir); changed != tt.changed {
+				t.Errorf("splitCollidingProjects() = %v, want %v", changed, tt.changed)
+			}
+			if !reflect.DeepEqual(tt.stats, tt.expected) {
+				t.Errorf("stats = %+v, want %+v", tt.stats, tt.expected)
+			}
+
+			// A second run finds nothing left to repair
+			if splitCollidingProjects(tt.stats, tt.results, seriesDir) {
+				t.Errorf("second splitCollidingProjects() = true, want false")
+			}
+			if !reflect.DeepEqual(tt.stats, tt.expected) {
+				t.Errorf("stats after a second run = %+v, want %+v", tt.stats, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteStatsRebasesExtractionChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous DayStats
+		results  map[string]ProjectResult
+		delta    int
+	}{
+		{
+			name:     "First run with extraction",
+			previous: DayStats{Projects: map[string]int{"Book": 1000, "Notes": 200}, Total: 1200, Categories: map[string]int{"manuscript": 1200}},
+			results: map[string]ProjectResult{
+				"Book":  {Name: "Book", Words: 800, Extraction: "prose", Categories: map[string]int{"manuscript": 800}},
+				"Notes": {Name: "Notes", Words: 250, Extraction: "prose", Categories: map[string]int{"manuscript": 250}},
+			},
+			delta: 0,
+		},
+		{
+			name:     "Same extraction",
+			previous: DayStats{Projects: map[string]int{"Book": 1000}, Total: 1000, Extraction: map[string]string{"Book": "prose"}},
+			results: map[string]ProjectResult{
+				"Book": {Name: "Book", Words: 1100, Extraction: "prose"},
+			},
+			delta: 100,
+		},
+		{
+			name:     "One project changed and one new",
+			previous: DayStats{Projects: map[string]int{"Book": 1000, "Notes": 200}, Total: 1200, Extraction: map[string]string{"Book": "prose"}},
+			results: map[string]ProjectResult{
+				"Book":  {Name: "Book", Words: 1100, Extraction: "prose"},
+				"Notes": {Name: "Notes", Words: 150, Extraction: "prose+code_blocks"},
+				"Essay": {Name: "Essay", Words: 50, Extraction: "prose"},
+			},
+			delta: 150,
+		},
+		{
+			name:     "Raw Markdown kept",
+			previous: DayStats{Projects: map[string]int{"Book": 1000}, Total: 1000},
+			results: map[string]ProjectResult{
+				"Book": {Name: "Book", Words: 1200, Extraction: "raw"},
+			},
+			delta: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			dataDir, err := getDataDir()
+			if err != nil {
+				t.Fatalf("getDataDir failed: %v", err)
+			}
+			path := filepath.Join(dataDir, "verkount_stats.yaml")
+			if err := writeStatsFile(path, StatsFile{"2020-01-01": tt.previous}); err != nil {
+				t.Fatalf("Failed to write stats: %v", err)
+			}
+
+			if err := WriteStats(tt.results, ""); err != nil {
+				t.Fatalf("WriteStats failed: %v", err)
+			}
+
+			today, _, found := getMostRecentStats(readStatsFile(path))
+			if !found {
+				t.Fatalf("no entry written")
+			}
+			if today.Delta != tt.delta {
+				t.Errorf("Delta = %d, want %d", today.Delta, tt.delta)
+			}
+			if len(today.CategoryDeltas) != 0 {
+				// None of the cases has category deltas to record
+				t.Errorf("CategoryDeltas = %v, want none", today.CategoryDeltas)
+			}
+			for key, result := range tt.results {
+				if today.Extraction[key] != result.Extraction {
+					t.Errorf("Extraction[%s] = %q, want %q", key, today.Extraction[key], result.Extraction)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryDeltas(t *testing.T) {
+	tests := []struct {
+		name       string
+		previous   DayStats
+		found      bool
+		categories map[string]int
+		expected   map[string]int
+	}{
+		{
+			name:       "First entry",
+			categories: map[string]int{"manuscript": 100},
+		},
+		{
+			name:       "Previous entry without categories",
+			previous:   DayStats{Projects: map[string]int{"Book": 100}, Total: 100},
+			found:      true,
+			categories: map[string]int{"manuscript": 120},
+		},
+		{
+			name:       "Unchanged",
+			previous:   DayStats{Categories: map[string]int{"manuscript": 100, "notes": 20}},
+			found:      true,
+			categories: map[string]int{"manuscript": 100, "notes": 20},
+			expected:   map[string]int{},
+		},
+		{
+			name:       "Words written and cut",
+			previous:   DayStats{Categories: map[string]int{"manuscript": 100, "notes": 20}},
+			found:      true,
+			categories: map[string]int{"manuscript": 90, "notes": 45},
+			expected:   map[string]int{"manuscript": -10, "notes": 25},
+		},
+		{
+			name:       "Category added and removed",
+			previous:   DayStats{Categories: map[string]int{"manuscript": 100, "research": 30}},
+			found:      true,
+			categories: map[string]int{"manuscript": 100, "notes": 15},
+			expected:   map[string]int{"notes": 15, "research": -30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categoryDeltas(tt.previous, tt.found, tt.categories); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("categoryDeltas() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCategoryTotals(t *testing.T) {
+	results := map[string]ProjectResult{
+		"Book":  {Words: 1300, Categories: map[string]int{"manuscript": 1000, "notes": 300}},
+		"Essay": {Words: 200, Categories: map[string]int{"manuscript": 200}},
+		"Wiki":  {Words: 50, Categories: map[string]int{"research": 50}},
+	}
+
+	expected := map[string]int{"manuscript": 1200, "notes": 300, "research": 50}
+	if got := categoryTotals(results); !reflect.DeepEqual(got, expected) {
+		t.Errorf("categoryTotals() = %v, want %v", got, expected)
+	}
+}
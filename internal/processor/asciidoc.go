@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	adocHeadingPattern     = regexp.MustCompile(`^(={1,6}|#{1,6})\s+(.*?)(?:\s+(?:=+|#+))?\s*$`)
+	adocAttributePattern   = regexp.MustCompile(`^:!?[\w-]+!?:`)
+	adocAttrRefPattern     = regexp.MustCompile(`\{[\w-]+\}`)
+	adocBlockAttrPattern   = regexp.MustCompile(`^\[[^\]]*\]\s*$`)
+	adocBlockTitlePattern  = regexp.MustCompile(`^\.[^.\s]`)
+	adocMacroLinePattern   = regexp.MustCompile(`^(?:image|include|video|audio|toc)::`)
+	adocAdmonitionPattern  = regexp.MustCompile(`^(?:NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+`)
+	adocListPattern        = regexp.MustCompile(`^\s*(?:[*.-]+|\d+\.)\s+(?:\[[ x*]\]\s+)?`)
+	adocLabeledPattern     = regexp.MustCompile(`(:{2,4}|;;)(\s+|$)`)
+	adocURLPattern         = regexp.MustCompile(`(?:https?|ftp|mailto|irc)://?[^\s\[]*\[([^\]]*)\]`)
+	adocMacroPattern       = regexp.MustCompile(`\b(?:link|xref|mailto|kbd|btn|menu|footnote|footnoteref|pass|stem|latexmath|asciimath):[^\s\[]*\[([^\]]*)\]`)
+	adocImagePattern       = regexp.MustCompile(`\bimage:[^\s\[]*\[[^\]]*\]`)
+	adocCrossRefPattern    = regexp.MustCompile(`<<[^,>]*,\s*([^>]*)>>|<<[^>]*>>`)
+	adocAnchorPattern      = regexp.MustCompile(`\[\[[^\]]*\]\]`)
+	adocRolePattern        = regexp.MustCompile(`\[[.#%][^\]]*\]([*_` + "`" + `#])`)
+	adocFormattingPattern  = regexp.MustCompile("[*_`#^~]+")
+	adocPassthroughPattern = regexp.MustCompile(`\+{1,3}([^+]*)\+{1,3}`)
+)
+
+// adocProseDelimiters are the block delimiters whose contents are counted
+var adocProseDelimiters = map[string]bool{
+	"====": true, "****": true, "____": true, "--": true, "|===": true, "!===": true,
+}
+
+// adocSkippedDelimiters are the block delimiters whose contents are not
+// counted: listings, literals, comments and passthroughs
+var adocSkippedDelimiters = map[string]bool{
+	"----": true, "....": true, "////": true, "++++": true,
+}
+
+// asciidocExtractor strips AsciiDoc markup: document attributes, section
+// markers, listing, literal and comment blocks, block attributes and titles,
+// macros, cross references and inline formatting
+type asciidocExtractor struct {
+	block string // Delimiter of an open block whose contents are skipped
+	level int
+	title string
+	held  string // Prose of a line that looks like a block title, until the next line shows whether a block follows
+}
+
+func (a *asciidocExtractor) heading() (int, string) {
+	return a.level, a.title
+}
+
+// line returns the prose in a line of AsciiDoc. A line such as .Title is
+// held back, and left out if the next line starts the block it names, so
+// prose such as ".NET is…" still counts.
+func (a *asciidocExtractor) line(line string) string {
+	held := a.held
+	a.held = ""
+	if held != "" && adocOpensBlock(strings.TrimSpace(strings.TrimSuffix(line, "\r"))) {
+		// It was the title of the block
+		held = ""
+	}
+
+	prose := a.extract(line)
+	if held == "" {
+		return prose
+	}
+	return held + " " + prose
+}
+
+// flush returns the prose of a line still held back at the end of the file
+func (a *asciidocExtractor) flush() string {
+	held := a.held
+	a.held = ""
+	return held
+}
+
+// extract returns the prose in a single line of AsciiDoc, holding back a
+// line that may be a block title
+func (a *asciidocExtractor) extract(line string) string {
+	a.level = 0
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+
+	if a.block != "" {
+		if trimmed == a.block {
+			a.block = ""
+		}
+		return ""
+	}
+
+	if delimiter, ok := adocDelimiter(trimmed); ok {
+		if adocSkippedDelimiters[delimiter] {
+			a.block = trimmed
+		}
+		return ""
+	}
+
+	if strings.HasPrefix(trimmed, "//") || trimmed == "+" ||
+		adocAttributePattern.MatchString(trimmed) || adocBlockAttrPattern.MatchString(trimmed) ||
+		adocMacroLinePattern.MatchString(trimmed) {
+		return ""
+	}
+
+	if m := adocHeadingPattern.FindStringSubmatch(line); m != nil {
+		// The document title (level 0) counts as a top level heading
+		a.level = len(m[1])
+		if a.level > 1 {
+			a.level--
+		}
+		a.title = stripAsciidocInline(m[2])
+		return a.title
+	}
+
+	if strings.HasPrefix(trimmed, "|") || strings.HasPrefix(trimmed, "!") {
+		// Table cells, with their separators and cell specifiers
+		line = strings.NewReplacer("|", " ", "!", " ").Replace(line)
+	}
+	line = adocAdmonitionPattern.ReplaceAllString(strings.TrimLeft(line, " \t"), "")
+	line = adocListPattern.ReplaceAllString(line, "")
+	line = adocLabeledPattern.ReplaceAllString(line, " ")
+	line = strings.TrimSuffix(strings.TrimRight(line, " \t"), " +")
+
+	if adocBlockTitlePattern.MatchString(trimmed) {
+		a.held = stripAsciidocInline(line)
+		return ""
+	}
+	return stripAsciidocInline(line)
+}
+
+// adocOpensBlock reports whether line starts a block that a block title
+// above it names: a delimited block, block attributes, a block macro or a list
+func adocOpensBlock(line string) bool {
+	_, delimiter := adocDelimiter(line)
+	return delimiter || adocBlockAttrPattern.MatchString(line) ||
+		adocMacroLinePattern.MatchString(line) || adocListPattern.MatchString(line)
+}
+
+// adocDelimiter returns the canonical form of a block delimiter line, which
+// may be longer than the minimum of four characters
+func adocDelimiter(line string) (string, bool) {
+	if line == "--" || line == "|===" || line == "!===" {
+		return line, true
+	}
+	if len(line) < 4 {
+		return "", false
+	}
+	for _, r := range line {
+		if r != rune(line[0]) {
+			return "", false
+		}
+	}
+	delimiter := line[:4]
+	return delimiter, adocProseDelimiters[delimiter] || adocSkippedDelimiters[delimiter]
+}
+
+// stripAsciidocInline removes attribute references, macros, cross references
+// and formatting marks from a line of AsciiDoc
+func stripAsciidocInline(line string) string {
+	line = adocAttrRefPattern.ReplaceAllString(line, "")
+	line = adocAnchorPattern.ReplaceAllString(line, "")
+	line = adocImagePattern.ReplaceAllString(line, "")
+	line = adocURLPattern.ReplaceAllString(line, "$1")
+	line = adocMacroPattern.ReplaceAllString(line, "$1")
+	line = adocCrossRefPattern.ReplaceAllString(line, "$1")
+	line = adocRolePattern.ReplaceAllString(line, "$1")
+	line = adocPassthroughPattern.ReplaceAllString(line, "$1")
+	line = adocFormattingPattern.ReplaceAllString(line, "")
+	return line
+}
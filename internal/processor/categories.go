@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/bwilson/verkounter/internal/ignore"
+)
+
+// DefaultCategory is the category of the files that no category claims
+const DefaultCategory = "manuscript"
+
+// Category names a part of a project, such as its notes or research, by
+// globs of its files and folders in .gitignore syntax
+type Category struct {
+	Name  string
+	Globs []string
+}
+
+// Categories are a project's categories in the order they are checked. A
+// file belongs to the first category whose globs match it.
+type Categories []Category
+
+// categoryMatcher assigns the files of a project to its categories
+type categoryMatcher struct {
+	names []string
+	globs []ignore.Globs
+}
+
+func newCategoryMatcher(categories Categories) (*categoryMatcher, error) {
+	m := &categoryMatcher{}
+	for _, category := range categories {
+		globs, err := ignore.CompileGlobs(category.Globs)
+		if err != nil {
+			return nil, fmt.Errorf("category %s: %v", category.Name, err)
+		}
+		m.names = append(m.names, category.Name)
+		m.globs = append(m.globs, globs)
+	}
+	return m, nil
+}
+
+// category returns the category of the file at the slash-separated path
+// relative to the project
+func (m *categoryMatcher) category(relPath string) string {
+	for i, globs := range m.globs {
+		if globs.Match(relPath) {
+			return m.names[i]
+		}
+	}
+	return DefaultCategory
+}
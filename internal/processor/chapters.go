@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"github.com/bwilson/verkounter/internal/counter"
+)
+
+// Section is the word count of a chapter or section of a project. Sections
+// start at headings up to the configured level and at the start of each file.
+type Section struct {
+	File    string // Slash-separated path of the file the section is in
+	Heading string // Heading text, empty for text before a file's first heading
+	Level   int    // Heading level, 0 for text before a file's first heading
+	Words   int
+}
+
+// Label returns the heading text, or the file name for text before a file's first heading
+func (s Section) Label() string {
+	if s.Heading == "" {
+		return s.File
+	}
+	return s.Heading
+}
+
+// sectionTracker splits the prose written to it into sections at headings
+type sectionTracker struct {
+	counter  counter.Counter
+	level    int // Deepest heading level that starts a new section
+	sections []Section
+	current  Section
+	tally    counter.Tally
+}
+
+func (t *sectionTracker) Write(p []byte) (int, error) {
+	return t.tally.Write(p)
+}
+
+// startFile begins a section for the text at the top of a file
+func (t *sectionTracker) startFile(file string) {
+	t.finish(0)
+	t.current = Section{File: file}
+	t.tally = t.counter.NewTally()
+}
+
+// heading starts a new section when a heading at or above the tracked level
+// appears. written is set for a heading known only from the underline below
+// its title, whose words were already written to the section before it.
+func (t *sectionTracker) heading(level int, text string, written bool) {
+	if level > t.level {
+		return
+	}
+
+	carried := 0
+	if written {
+		carried = t.counter.Count(text)
+	}
+
+	// A file that opens with a heading takes it as the label of its first section
+	if t.current.Heading == "" && t.tally.Count() == carried {
+		t.current.Heading = text
+		t.current.Level = level
+		return
+	}
+
+	file := t.current.File
+	t.finish(carried)
+	t.current = Section{File: file, Heading: text, Level: level}
+	t.tally = t.counter.NewTally()
+	if written {
+		t.tally.WriteString(text)
+	}
+}
+
+// finish records the current section, less the carried words that belong to
+// the next one, dropping empty text before a file's first heading
+func (t *sectionTracker) finish(carried int) {
+	if t.tally == nil {
+		return
+	}
+
+	t.current.Words = t.tally.Count() - carried
+	if t.current.Heading != "" || t.current.Words > 0 {
+		t.sections = append(t.sections, t.current)
+	}
+	t.tally = nil
+}
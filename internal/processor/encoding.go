@@ -0,0 +1,236 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encodings that text files are read in
+const (
+	encodingUTF16LE     = "utf-16le"
+	encodingUTF16BE     = "utf-16be"
+	encodingWindows1252 = "windows-1252"
+)
+
+// sniffBytes is how much of a file is examined for a byte order mark or for
+// UTF-16 text without one
+const sniffBytes = 4096
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// FileEncoding records how a file that was not plain UTF-8 was read
+type FileEncoding struct {
+	Name    string // utf-16le, utf-16be or windows-1252
+	Guessed bool   // No byte order mark declared the encoding
+}
+
+// textFile reads a text file as UTF-8, whatever its encoding
+type textFile struct {
+	io.Reader
+	file     *os.File
+	doc      *Document
+	fellBack bool // Bytes that are not UTF-8 were read as Windows-1252
+}
+
+// openText opens a text file for reading as UTF-8. A byte order mark selects
+// UTF-8 or UTF-16; without one, UTF-16 is recognised by its NUL bytes, and
+// bytes that are not valid UTF-8 are read as Windows-1252. The encoding is
+// recorded in doc when the file was not plain UTF-8. A file that looks like
+// binary content is not opened.
+func openText(path string, doc *Document) (*textFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReaderSize(file, sniffBytes)
+	head, err := reader.Peek(sniffBytes)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+
+	t := &textFile{file: file, doc: doc}
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		reader.Discard(len(utf8BOM))
+		t.Reader = &decodingReader{r: reader, decode: t.decodeUTF8}
+	case bytes.HasPrefix(head, utf16LEBOM):
+		reader.Discard(len(utf16LEBOM))
+		doc.encoding = FileEncoding{Name: encodingUTF16LE}
+		t.Reader = &decodingReader{r: reader, decode: utf16Decoder(binary.LittleEndian)}
+	case bytes.HasPrefix(head, utf16BEBOM):
+		reader.Discard(len(utf16BEBOM))
+		doc.encoding = FileEncoding{Name: encodingUTF16BE}
+		t.Reader = &decodingReader{r: reader, decode: utf16Decoder(binary.BigEndian)}
+	default:
+		switch sniffUTF16(head) {
+		case encodingUTF16LE:
+			doc.encoding = FileEncoding{Name: encodingUTF16LE, Guessed: true}
+			t.Reader = &decodingReader{r: reader, decode: utf16Decoder(binary.LittleEndian)}
+		case encodingUTF16BE:
+			doc.encoding = FileEncoding{Name: encodingUTF16BE, Guessed: true}
+			t.Reader = &decodingReader{r: reader, decode: utf16Decoder(binary.BigEndian)}
+		default:
+			if looksBinary(head) {
+				file.Close()
+				return nil, skipError{"binary content"}
+			}
+			t.Reader = &decodingReader{r: reader, decode: t.decodeUTF8}
+		}
+	}
+
+	return t, nil
+}
+
+// Close closes the file, recording a guessed Windows-1252 encoding once all
+// of it has been read
+func (t *textFile) Close() error {
+	if t.fellBack {
+		t.doc.encoding = FileEncoding{Name: encodingWindows1252, Guessed: true}
+	}
+	return t.file.Close()
+}
+
+// decodeUTF8 passes UTF-8 through, reading any byte that is not part of a
+// valid UTF-8 sequence as Windows-1252
+func (t *textFile) decodeUTF8(src []byte, eof bool, dst []byte) ([]byte, int) {
+	i := 0
+	for i < len(src) {
+		if src[i] < utf8.RuneSelf {
+			dst = append(dst, src[i])
+			i++
+			continue
+		}
+		if !eof && !utf8.FullRune(src[i:]) {
+			// The rest of the sequence has not been read yet
+			break
+		}
+
+		r, size := utf8.DecodeRune(src[i:])
+		if r == utf8.RuneError && size == 1 {
+			t.fellBack = true
+			dst = utf8.AppendRune(dst, decodeWindows1252(src[i]))
+			i++
+			continue
+		}
+		dst = append(dst, src[i:i+size]...)
+		i += size
+	}
+	return dst, i
+}
+
+// utf16Decoder returns a decoder from UTF-16 in the given byte order to UTF-8.
+// Unpaired surrogates become the replacement character.
+func utf16Decoder(order binary.ByteOrder) func(src []byte, eof bool, dst []byte) ([]byte, int) {
+	return func(src []byte, eof bool, dst []byte) ([]byte, int) {
+		i := 0
+		for i+1 < len(src) {
+			unit := rune(order.Uint16(src[i:]))
+			if !utf16.IsSurrogate(unit) {
+				dst = utf8.AppendRune(dst, unit)
+				i += 2
+				continue
+			}
+			if i+3 >= len(src) && !eof {
+				// The second half of the pair has not been read yet
+				break
+			}
+
+			r := utf8.RuneError
+			if i+3 < len(src) {
+				r = utf16.DecodeRune(unit, rune(order.Uint16(src[i+2:])))
+			}
+			if r == utf8.RuneError {
+				dst = utf8.AppendRune(dst, r)
+				i += 2
+				continue
+			}
+			dst = utf8.AppendRune(dst, r)
+			i += 4
+		}
+		if eof {
+			// A stray odd byte at the end is dropped
+			i = len(src)
+		}
+		return dst, i
+	}
+}
+
+// sniffUTF16 recognises UTF-16 text without a byte order mark by the NUL
+// bytes that Latin text has in every other byte, returning its encoding or ""
+func sniffUTF16(head []byte) string {
+	pairs := len(head) / 2
+	if pairs < 2 {
+		return ""
+	}
+
+	evenNULs, oddNULs := 0, 0
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenNULs++
+		}
+		if head[i+1] == 0 {
+			oddNULs++
+		}
+	}
+
+	switch {
+	case oddNULs*10 >= pairs*4 && evenNULs*20 < pairs:
+		return encodingUTF16LE
+	case evenNULs*10 >= pairs*4 && oddNULs*20 < pairs:
+		return encodingUTF16BE
+	}
+	return ""
+}
+
+// decodingReader converts a stream to UTF-8 as it is read, holding only the
+// bytes of an incomplete character between reads
+type decodingReader struct {
+	r      io.Reader
+	decode func(src []byte, eof bool, dst []byte) ([]byte, int)
+	chunk  []byte // Storage for each read from r
+	src    []byte // Bytes read but not yet decoded
+	buf    []byte // Storage for decoded text
+	out    []byte // Decoded text not yet returned
+	eof    bool
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.eof && len(d.src) == 0 {
+			return 0, io.EOF
+		}
+
+		if !d.eof {
+			if d.chunk == nil {
+				d.chunk = make([]byte, sniffBytes)
+			}
+			n, err := d.r.Read(d.chunk)
+			d.src = append(d.src, d.chunk[:n]...)
+			if err == io.EOF {
+				d.eof = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		var consumed int
+		d.buf, consumed = d.decode(d.src, d.eof, d.buf[:0])
+		d.out = d.buf
+		d.src = append(d.src[:0], d.src[consumed:]...)
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
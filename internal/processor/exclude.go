@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	exclusionOffPattern = regexp.MustCompile(`<!--\s*verkount:off\s*-->`)
+	exclusionOnPattern  = regexp.MustCompile(`<!--\s*verkount:on\s*-->`)
+	commentOffPattern   = regexp.MustCompile(`%\s*verkount:off\b`)
+	commentOnPattern    = regexp.MustCompile(`%\s*verkount:on\b`)
+)
+
+// exclusionFilter separates text hidden by exclusion markers from the text
+// that counts. Everything between <!-- verkount:off --> and
+// <!-- verkount:on --> is excluded, as is everything between a pair of %%
+// markers. Both kinds of region may span lines. A %% straight after a letter
+// or digit is a percent sign rather than a marker.
+//
+// In formats where % starts a comment, such as LaTeX, %% pairs are not
+// markers; % verkount:off and % verkount:on comments are used instead.
+type exclusionFilter struct {
+	off             bool // Inside a verkount:off region
+	inPercent       bool // Inside a %% comment
+	percentComments bool // % starts a comment in the format being filtered
+}
+
+// line splits a single line into its counted and excluded parts. Markers
+// inside the byte ranges in code, such as Markdown code spans, are literal text.
+func (f *exclusionFilter) line(line string, code [][2]int) (string, string) {
+	var kept, excluded strings.Builder
+
+	offPattern, onPattern := exclusionOffPattern, exclusionOnPattern
+	if f.percentComments {
+		offPattern, onPattern = commentOffPattern, commentOnPattern
+	}
+
+	// Markers are looked for in a copy of the line with its code blanked out,
+	// which keeps the positions of everything else
+	search := maskCode(line, code)
+
+	for pos := 0; pos < len(line); {
+		if f.inPercent {
+			end := strings.Index(search[pos:], "%%")
+			if end < 0 {
+				excluded.WriteString(line[pos:])
+				break
+			}
+			excluded.WriteString(line[pos : pos+end])
+			pos += end + 2
+			f.inPercent = false
+			continue
+		}
+
+		if f.off {
+			loc := onPattern.FindStringIndex(search[pos:])
+			if loc == nil {
+				excluded.WriteString(line[pos:])
+				break
+			}
+			excluded.WriteString(line[pos : pos+loc[0]])
+			pos += loc[1]
+			f.off = false
+			continue
+		}
+
+		off := offPattern.FindStringIndex(search[pos:])
+		percent := -1
+		if !f.percentComments {
+			percent = openingPercent(search, pos)
+		}
+
+		switch {
+		case percent >= 0 && (off == nil || percent < pos+off[0]):
+			kept.WriteString(line[pos:percent])
+			pos = percent + 2
+			f.inPercent = true
+		case off != nil:
+			kept.WriteString(line[pos : pos+off[0]])
+			pos += off[1]
+			f.off = true
+		default:
+			kept.WriteString(line[pos:])
+			pos = len(line)
+		}
+	}
+
+	return kept.String(), excluded.String()
+}
+
+// openingPercent returns the position of the first %% in line at or after
+// from that opens a comment, or -1 if there is none. A %% straight after a
+// letter or digit, as in 100%%, is literal text.
+func openingPercent(line string, from int) int {
+	for {
+		i := strings.Index(line[from:], "%%")
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if r, _ := utf8.DecodeLastRuneInString(line[:i]); i == 0 || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return i
+		}
+		from = i + 2
+	}
+}
+
+// maskCode returns line with the bytes in the code ranges replaced by NULs
+func maskCode(line string, code [][2]int) string {
+	if len(code) == 0 {
+		return line
+	}
+	masked := []byte(line)
+	for _, span := range code {
+		for i := span[0]; i < span[1]; i++ {
+			masked[i] = 0
+		}
+	}
+	return string(masked)
+}
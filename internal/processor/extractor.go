@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Extractor pulls the readable prose out of one file format
+type Extractor interface {
+	// Name identifies the format, e.g. "markdown"
+	Name() string
+	// Extract reads the file at path and writes its prose to doc
+	Extract(path string, opts Options, doc *Document) error
+}
+
+// DefaultExtensions are the file extensions counted when a project does not
+// list its own
+var DefaultExtensions = []string{".md", ".scriv"}
+
+var extractors = make(map[string]Extractor)
+
+func init() {
+	RegisterExtractor(".md", markdownFormat{})
+	RegisterExtractor(".markdown", markdownFormat{})
+	RegisterExtractor(".txt", textFormat{name: "text", newMarkup: func(Options) markupStripper { return plainText{} }})
+	RegisterExtractor(".org", textFormat{name: "org", newMarkup: func(Options) markupStripper { return &orgExtractor{} }})
+	RegisterExtractor(".rst", textFormat{name: "restructuredtext", newMarkup: func(Options) markupStripper { return &rstExtractor{} }})
+	RegisterExtractor(".adoc", textFormat{name: "asciidoc", newMarkup: func(Options) markupStripper { return &asciidocExtractor{} }})
+	RegisterExtractor(".asciidoc", textFormat{name: "asciidoc", newMarkup: func(Options) markupStripper { return &asciidocExtractor{} }})
+	RegisterExtractor(".docx", docxFormat{})
+	RegisterExtractor(".odt", odtFormat{})
+	RegisterExtractor(".rtf", rtfFormat{})
+	RegisterExtractor(".scriv", scrivenerFormat{})
+	RegisterExtractor(".fountain", fountainFormat{})
+	RegisterExtractor(".ipynb", notebookFormat{})
+	RegisterExtractor(".html", htmlFormat{})
+	RegisterExtractor(".htm", htmlFormat{})
+	RegisterExtractor(".xhtml", htmlFormat{})
+	RegisterExtractor(".epub", epubFormat{})
+	RegisterExtractor(".qmd", literateFormat{name: "quarto"})
+	RegisterExtractor(".rmd", literateFormat{name: "rmarkdown"})
+	RegisterExtractor(".tex", textFormat{name: "latex", percentComments: true, newMarkup: func(opts Options) markupStripper { return &latexExtractor{opts: opts.LaTeX} }})
+}
+
+// RegisterExtractor makes an extractor available for files with the given
+// extension, replacing any extractor previously registered for it
+func RegisterExtractor(extension string, e Extractor) {
+	extractors[NormalizeExtension(extension)] = e
+}
+
+// ExtractorFor returns the extractor registered for an extension
+func ExtractorFor(extension string) (Extractor, bool) {
+	e, ok := extractors[NormalizeExtension(extension)]
+	return e, ok
+}
+
+// Extensions returns every extension with a registered extractor in sorted order
+func Extensions() []string {
+	var extensions []string
+	for extension := range extractors {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// NormalizeExtension lower-cases an extension and adds its leading dot, so
+// "TXT", "txt" and ".txt" are treated alike
+func NormalizeExtension(extension string) string {
+	extension = strings.ToLower(strings.TrimSpace(extension))
+	if extension != "" && !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
+
+// enabledExtractors maps each enabled extension to its extractor, returning
+// an error for extensions that have none
+func enabledExtractors(extensions []string) (map[string]Extractor, error) {
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+
+	enabled := make(map[string]Extractor)
+	for _, extension := range extensions {
+		e, ok := ExtractorFor(extension)
+		if !ok {
+			return nil, fmt.Errorf("no extractor for %q files (supported: %s)", extension, strings.Join(Extensions(), ", "))
+		}
+		enabled[NormalizeExtension(extension)] = e
+	}
+
+	return enabled, nil
+}
+
+// markdownFormat extracts prose from Markdown, stripping YAML frontmatter
+type markdownFormat struct{}
+
+func (markdownFormat) Name() string { return "markdown" }
+
+func (markdownFormat) Extract(path string, opts Options, doc *Document) error {
+	newMarkup := func() markupStripper { return &markdownExtractor{opts: opts.Markdown} }
+	return extractText(path, doc, newMarkup, true, opts.Markdown.Raw)
+}
+
+// textFormat extracts prose from a line-based markup format
+type textFormat struct {
+	name            string
+	newMarkup       func(opts Options) markupStripper
+	percentComments bool // % starts a comment, so %% pairs are not exclusion markers
+}
+
+func (f textFormat) Name() string { return f.name }
+
+func (f textFormat) Extract(path string, opts Options, doc *Document) error {
+	newMarkup := func() markupStripper { return f.newMarkup(opts) }
+	doc.filter.percentComments = f.percentComments
+	return extractText(path, doc, newMarkup, false, false)
+}
+
+// plainText counts every line of a plain text file as prose
+type plainText struct{}
+
+func (plainText) line(line string) string { return strings.TrimSuffix(line, "\r") }
+
+func (plainText) heading() (int, string) { return 0, "" }
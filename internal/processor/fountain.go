@@ -0,0 +1,302 @@
+package processor
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwilson/verkounter/internal/counter"
+)
+
+// Standard screenplay layout: 55 lines to a page, with action set 61
+// characters wide, dialogue 35 and parentheticals 25
+const (
+	screenplayLinesPerPage     = 55
+	screenplayActionWidth      = 61
+	screenplayDialogueWidth    = 35
+	screenplayParentheticWidth = 25
+)
+
+var (
+	fountainScenePattern      = regexp.MustCompile(`(?i)^(?:int|ext|est|int\.?/ext|i/e)[. ]`)
+	fountainSceneNumber       = regexp.MustCompile(`\s*#[\w.-]+#\s*$`)
+	fountainTitleKey          = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*:`)
+	fountainTransitionPattern = regexp.MustCompile(`^[^a-z]*TO:$`)
+	fountainExtensionPattern  = regexp.MustCompile(`\([^)]*\)`)
+	fountainEmphasisPattern   = regexp.MustCompile(`\\?[*_]+`)
+)
+
+// Screenplay holds the metrics of a project's Fountain screenplays
+type Screenplay struct {
+	Scenes        int     // Scene headings
+	Pages         float64 // Estimated page count under standard screenplay formatting
+	DialogueWords int     // Words spoken by characters
+	ActionWords   int     // Words of action and description
+}
+
+// DialogueRatio returns the share of dialogue among dialogue and action words
+func (s Screenplay) DialogueRatio() float64 {
+	if s.DialogueWords+s.ActionWords == 0 {
+		return 0
+	}
+	return float64(s.DialogueWords) / float64(s.DialogueWords+s.ActionWords)
+}
+
+// screenplayTracker accumulates screenplay metrics across a project's files
+type screenplayTracker struct {
+	counter  counter.Counter
+	dialogue counter.Tally
+	action   counter.Tally
+	scenes   int
+	lines    int // Lines the screenplay fills on the page
+	used     bool
+}
+
+func newScreenplayTracker(c counter.Counter) *screenplayTracker {
+	return &screenplayTracker{counter: c, dialogue: c.NewTally(), action: c.NewTally()}
+}
+
+// result returns the metrics gathered so far
+func (t *screenplayTracker) result() Screenplay {
+	pages := float64(t.lines) / screenplayLinesPerPage
+	return Screenplay{
+		Scenes:        t.scenes,
+		Pages:         math.Round(pages*10) / 10,
+		DialogueWords: t.dialogue.Count(),
+		ActionWords:   t.action.Count(),
+	}
+}
+
+// fountainFormat extracts prose and screenplay metrics from Fountain files
+type fountainFormat struct{}
+
+func (fountainFormat) Name() string { return "fountain" }
+
+func (fountainFormat) Extract(path string, opts Options, doc *Document) error {
+	file, err := openText(path, doc)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	screenplay := doc.screenplay
+	if screenplay == nil {
+		// Metrics are only gathered when the processor asks for them
+		screenplay = newScreenplayTracker(opts.Counter)
+	}
+	screenplay.used = true
+
+	parser := &fountainParser{doc: doc, screenplay: screenplay, titlePage: true}
+	if err := readLines(file, parser.line); err != nil {
+		return err
+	}
+	parser.flush()
+
+	return nil
+}
+
+// fountainParser groups the lines of a Fountain file into blocks separated by
+// blank lines and classifies each block as a screenplay element
+type fountainParser struct {
+	doc        *Document
+	screenplay *screenplayTracker
+	titlePage  bool     // The first block may still be a title page
+	inBoneyard bool     // Inside a /* boneyard */ section
+	inNote     bool     // Inside a [[note]]
+	block      []string // Lines of the current block
+}
+
+func (p *fountainParser) line(line string, continued bool) {
+	line = p.stripComments(strings.TrimSuffix(line, "\r"))
+	trimmed := strings.TrimSpace(line)
+
+	// Sections, synopses and page breaks outline the script but are not part of it
+	if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "=") {
+		return
+	}
+
+	if trimmed == "" && !continued {
+		p.flush()
+		return
+	}
+	if continued && len(p.block) > 0 {
+		p.block[len(p.block)-1] += line
+		return
+	}
+	p.block = append(p.block, line)
+
+	if len(p.block) > maxFrontmatterLines {
+		p.flush()
+	}
+}
+
+// stripComments removes boneyard sections and notes, which may span lines
+func (p *fountainParser) stripComments(line string) string {
+	var kept strings.Builder
+	for line != "" {
+		switch {
+		case p.inBoneyard:
+			end := strings.Index(line, "*/")
+			if end < 0 {
+				return kept.String()
+			}
+			p.inBoneyard = false
+			line = line[end+2:]
+		case p.inNote:
+			end := strings.Index(line, "]]")
+			if end < 0 {
+				return kept.String()
+			}
+			p.inNote = false
+			line = line[end+2:]
+		default:
+			boneyard := strings.Index(line, "/*")
+			note := strings.Index(line, "[[")
+			if boneyard < 0 && note < 0 {
+				kept.WriteString(line)
+				return kept.String()
+			}
+			if note < 0 || boneyard >= 0 && boneyard < note {
+				kept.WriteString(line[:boneyard])
+				p.inBoneyard = true
+				line = line[boneyard+2:]
+			} else {
+				kept.WriteString(line[:note])
+				p.inNote = true
+				line = line[note+2:]
+			}
+		}
+	}
+	return kept.String()
+}
+
+// flush classifies and writes the current block
+func (p *fountainParser) flush() {
+	block := p.block
+	p.block = nil
+	if len(block) == 0 {
+		return
+	}
+
+	titlePage := p.titlePage
+	p.titlePage = false
+	if titlePage && fountainTitleKey.MatchString(strings.TrimSpace(block[0])) {
+		return
+	}
+
+	first := strings.TrimSpace(block[0])
+	switch {
+	case strings.HasPrefix(first, "!"):
+		block[0] = strings.TrimPrefix(strings.TrimLeft(block[0], " \t"), "!")
+		p.action(block)
+
+	case strings.HasPrefix(first, ".") && !strings.HasPrefix(first, ".."), fountainScenePattern.MatchString(first):
+		heading := fountainSceneNumber.ReplaceAllString(strings.TrimPrefix(first, "."), "")
+		p.screenplay.scenes++
+		p.screenplay.lines += 2
+		p.doc.Text(stripFountainEmphasis(heading))
+		p.action(block[1:])
+
+	case len(block) == 1 && (strings.HasPrefix(first, ">") && !strings.HasSuffix(first, "<") || fountainTransitionPattern.MatchString(first)):
+		p.screenplay.lines += 2
+		p.doc.Text(strings.TrimPrefix(first, ">"))
+
+	case len(block) > 1 && isCharacterCue(first):
+		p.dialogue(block)
+
+	default:
+		p.action(block)
+	}
+}
+
+// action writes action lines, including centered text and lyrics
+func (p *fountainParser) action(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	p.screenplay.lines++ // Blank line before the block
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "~")
+		if strings.HasPrefix(line, ">") && strings.HasSuffix(line, "<") {
+			line = strings.TrimSuffix(strings.TrimPrefix(line, ">"), "<")
+		}
+		line = stripFountainEmphasis(line)
+
+		p.screenplay.lines += wrappedLines(line, screenplayActionWidth)
+		p.doc.write(line, p.screenplay.action)
+	}
+}
+
+// dialogue writes a character cue followed by parentheticals and speech
+func (p *fountainParser) dialogue(lines []string) {
+	p.screenplay.lines += 2 // Blank line and the character cue
+
+	cue := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(lines[0]), "@"), "^")
+	p.doc.Text(strings.TrimSpace(fountainExtensionPattern.ReplaceAllString(cue, "")))
+
+	for _, line := range lines[1:] {
+		line = stripFountainEmphasis(strings.TrimSpace(line))
+		if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
+			p.screenplay.lines += wrappedLines(line, screenplayParentheticWidth)
+			p.doc.Text(line)
+			continue
+		}
+
+		p.screenplay.lines += wrappedLines(line, screenplayDialogueWidth)
+		p.doc.write(strings.TrimPrefix(line, "~"), p.screenplay.dialogue)
+	}
+}
+
+// isCharacterCue reports whether line names a speaking character: forced with
+// @, or upper case apart from an extension such as (V.O.)
+func isCharacterCue(line string) bool {
+	if strings.HasPrefix(line, "@") {
+		return true
+	}
+
+	name := strings.TrimSuffix(fountainExtensionPattern.ReplaceAllString(line, ""), "^")
+	hasLetter := false
+	for _, r := range name {
+		if unicode.IsLower(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+// stripFountainEmphasis removes the *, ** and _ emphasis markers
+func stripFountainEmphasis(line string) string {
+	return fountainEmphasisPattern.ReplaceAllStringFunc(line, func(marker string) string {
+		if strings.HasPrefix(marker, `\`) {
+			return marker[1:]
+		}
+		return ""
+	})
+}
+
+// wrappedLines returns how many lines text fills when wrapped at width characters
+func wrappedLines(text string, width int) int {
+	lines, length := 1, 0
+	for _, word := range strings.Fields(text) {
+		n := utf8.RuneCountInString(word)
+		switch {
+		case length == 0:
+			length = n
+		case length+1+n <= width:
+			length += 1 + n
+		default:
+			lines++
+			length = n
+		}
+		for length > width {
+			lines++
+			length -= width
+		}
+	}
+	return lines
+}
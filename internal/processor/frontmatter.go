@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Frontmatter formats recognised at the top of a text file
+const (
+	yamlFrontmatter = "yaml" // Between --- lines
+	tomlFrontmatter = "toml" // Between +++ lines
+	jsonFrontmatter = "json" // A JSON object opening the file
+)
+
+// openingFrontmatter returns the format of the frontmatter opened by the
+// first line of content, or "" if the line does not open one
+func openingFrontmatter(line string) string {
+	switch trimmed := strings.TrimSpace(line); {
+	case trimmed == "---":
+		return yamlFrontmatter
+	case trimmed == "+++":
+		return tomlFrontmatter
+	case trimmed == "{" || strings.HasPrefix(trimmed, `{"`):
+		return jsonFrontmatter
+	}
+	return ""
+}
+
+// closesFrontmatter reports whether line closes YAML or TOML frontmatter of
+// the given format. JSON frontmatter closes when its braces balance.
+func closesFrontmatter(format, line string) bool {
+	switch strings.TrimSpace(line) {
+	case "---":
+		return format == yamlFrontmatter
+	case "+++":
+		return format == tomlFrontmatter
+	}
+	return false
+}
+
+// parseFrontmatter decodes the body of a frontmatter block into a map
+func parseFrontmatter(format, body string) (map[string]any, error) {
+	metadata := make(map[string]any)
+
+	switch format {
+	case yamlFrontmatter:
+		if err := yaml.Unmarshal([]byte(body), &metadata); err != nil {
+			return nil, err
+		}
+	case jsonFrontmatter:
+		if err := json.Unmarshal([]byte(body), &metadata); err != nil {
+			return nil, err
+		}
+	case tomlFrontmatter:
+		if _, err := toml.Decode(body, &metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	return metadata, nil
+}
+
+// jsonDepth returns the nesting depth of brackets and braces after line,
+// starting from depth and ignoring brackets inside strings. inString carries
+// an unterminated string from line to line.
+func jsonDepth(line string, depth int, inString *bool) int {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case *inString && c == '\\':
+			i++
+		case c == '"':
+			*inString = !*inString
+		case *inString:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return depth
+}
@@ -0,0 +1,238 @@
+package processor
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlSkippedElements hold no prose: scripts, styles, navigation and the
+// document head
+var htmlSkippedElements = map[string]bool{
+	"script": true, "style": true, "nav": true, "head": true, "noscript": true,
+	"template": true, "svg": true, "math": true, "iframe": true, "object": true,
+}
+
+// htmlBlockElements start and end a paragraph of text
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "dt": true, "dd": true, "tr": true,
+	"td": true, "th": true, "blockquote": true, "pre": true, "section": true, "article": true,
+	"aside": true, "header": true, "footer": true, "figcaption": true, "table": true,
+	"ul": true, "ol": true, "dl": true, "hr": true, "body": true, "main": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// htmlFormat extracts prose from HTML and XHTML documents
+type htmlFormat struct{}
+
+func (htmlFormat) Name() string { return "html" }
+
+func (htmlFormat) Extract(filePath string, opts Options, doc *Document) error {
+	file, err := openText(filePath, doc)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractHTML(file, doc)
+}
+
+// extractHTML writes the text of an HTML document to doc one block at a time,
+// skipping scripts, styles and navigation. Comments are never text. The
+// document is read with an HTML tokenizer, so scripts and styles are raw text
+// and a bare < in prose is just a character.
+func extractHTML(r io.Reader, doc *Document) error {
+	tokenizer := html.NewTokenizer(r)
+
+	var text strings.Builder
+	level := 0     // Level of the heading being read
+	skipTag := ""  // Name of the element being left out
+	skipDepth := 0 // Nesting of skipTag inside the element being left out
+
+	flush := func() {
+		if level > 0 {
+			doc.Heading(level, text.String())
+		} else if strings.TrimSpace(text.String()) != "" {
+			doc.Text(text.String())
+		}
+		text.Reset()
+		level = 0
+	}
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return err
+			}
+			flush()
+			return nil
+
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if skipTag != "" {
+				if tag == skipTag {
+					skipDepth++
+				}
+				continue
+			}
+			if htmlSkippedElements[tag] || isNavigation(tokenizer) {
+				skipTag, skipDepth = tag, 1
+				continue
+			}
+			if htmlBlockElements[tag] {
+				flush()
+				if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+					level = int(tag[1] - '0')
+				}
+			}
+
+		case html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if skipTag == "" && htmlBlockElements[string(name)] {
+				flush()
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if skipTag != "" {
+				if tag == skipTag {
+					if skipDepth--; skipDepth == 0 {
+						skipTag = ""
+					}
+				}
+				continue
+			}
+			if htmlBlockElements[tag] {
+				flush()
+			}
+
+		case html.TextToken:
+			if skipTag == "" {
+				// Line breaks in the source are only whitespace
+				text.WriteString(strings.ReplaceAll(string(tokenizer.Text()), "\n", " "))
+			}
+		}
+	}
+}
+
+// isNavigation reports whether the current start tag is marked as a table of
+// contents, landmarks or page list, as EPUB navigation documents mark theirs
+func isNavigation(tokenizer *html.Tokenizer) bool {
+	for more := true; more; {
+		var key, value []byte
+		key, value, more = tokenizer.TagAttr()
+		switch string(key) {
+		case "epub:type":
+			for _, value := range strings.Fields(string(value)) {
+				if value == "toc" || value == "landmarks" || value == "page-list" {
+					return true
+				}
+			}
+		case "role":
+			if strings.HasPrefix(string(value), "doc-toc") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// epubContainer is META-INF/container.xml, which locates the package document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the part of the OPF package document that orders the book
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// epubFormat extracts prose from EPUB books: the XHTML documents of the
+// spine in reading order, leaving out navigation documents
+type epubFormat struct{}
+
+func (epubFormat) Name() string { return "epub" }
+
+func (epubFormat) Extract(filePath string, opts Options, doc *Document) error {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	parts := zipParts(archive)
+
+	var container epubContainer
+	if err := decodeXMLPart(parts, "META-INF/container.xml", &container); err != nil {
+		return fmt.Errorf("%s is not an EPUB: %v", filePath, err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return fmt.Errorf("%s is not an EPUB: no package document", filePath)
+	}
+
+	packagePath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeXMLPart(parts, packagePath, &pkg); err != nil {
+		return fmt.Errorf("%s: %v", filePath, err)
+	}
+
+	type manifestItem struct {
+		href string
+		nav  bool
+	}
+	items := make(map[string]manifestItem)
+	for _, item := range pkg.Manifest {
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		items[item.ID] = manifestItem{
+			href: path.Join(path.Dir(packagePath), href),
+			nav:  strings.Contains(" "+item.Properties+" ", " nav ") || item.MediaType == "application/x-dtbncx+xml",
+		}
+	}
+
+	for _, ref := range pkg.Spine {
+		item, ok := items[ref.IDRef]
+		if !ok || item.nav {
+			continue
+		}
+		part, ok := parts[item.href]
+		if !ok {
+			return fmt.Errorf("%s: spine document %s is missing", filePath, item.href)
+		}
+		if err := readPart(part, func(r io.Reader) error { return extractHTML(r, doc) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeXMLPart decodes the named file of a zip archive into v
+func decodeXMLPart(parts map[string]*zip.File, name string, v any) error {
+	part, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("%s is missing", name)
+	}
+	return readPart(part, func(r io.Reader) error { return xml.NewDecoder(r).Decode(v) })
+}
@@ -0,0 +1,346 @@
+package processor
+
+import (
+	"strings"
+)
+
+// LaTeXOptions controls which parts of a LaTeX document count as prose. The
+// zero value counts the body text including the bibliography and figure
+// captions; comments, the preamble, math and command names are never counted.
+type LaTeXOptions struct {
+	SkipBibliography bool // Leave the thebibliography environment out of the count
+	SkipCaptions     bool // Leave figure and table captions out of the count
+}
+
+// latexHeadingLevels maps sectioning commands to heading levels
+var latexHeadingLevels = map[string]int{
+	"part": 1, "chapter": 1, "section": 2, "subsection": 3, "subsubsection": 4,
+	"paragraph": 5, "subparagraph": 6,
+}
+
+// latexSkippedEnvironments are the environments whose contents are not prose
+var latexSkippedEnvironments = map[string]bool{
+	"equation": true, "equation*": true, "align": true, "align*": true, "alignat": true,
+	"alignat*": true, "flalign": true, "flalign*": true, "gather": true, "gather*": true,
+	"multline": true, "multline*": true, "eqnarray": true, "eqnarray*": true,
+	"displaymath": true, "math": true, "verbatim": true, "verbatim*": true, "Verbatim": true,
+	"lstlisting": true, "minted": true, "comment": true, "tikzpicture": true, "filecontents": true,
+}
+
+// latexDroppedCommands are the commands whose arguments are not prose, such as
+// references, citations, file names and lengths
+var latexDroppedCommands = map[string]bool{
+	"label": true, "ref": true, "eqref": true, "pageref": true, "autoref": true, "cref": true, "Cref": true,
+	"cite": true, "citep": true, "citet": true, "autocite": true, "parencite": true, "textcite": true,
+	"footcite": true, "nocite": true, "url": true, "includegraphics": true, "input": true, "include": true,
+	"bibliography": true, "bibliographystyle": true, "addbibresource": true, "usepackage": true,
+	"documentclass": true, "vspace": true, "hspace": true, "setlength": true, "setcounter": true,
+	"addtocounter": true, "newcommand": true, "renewcommand": true, "providecommand": true,
+	"newenvironment": true, "hypersetup": true, "pagestyle": true, "thispagestyle": true,
+	"color": true, "fontsize": true, "index": true, "graphicspath": true, "geometry": true,
+}
+
+// latexFirstArgumentDropped are the commands whose first argument is not prose
+// but whose later arguments are, e.g. \href{url}{text}
+var latexFirstArgumentDropped = map[string]bool{
+	"href": true, "textcolor": true, "colorbox": true,
+}
+
+// latexArgumentEnvironments are the environments whose \begin takes an
+// argument that is not prose, such as a table's column specification
+var latexArgumentEnvironments = map[string]bool{
+	"tabular": true, "tabular*": true, "tabularx": true, "longtable": true, "array": true,
+	"minipage": true, "multicols": true,
+}
+
+// latexExtractor strips LaTeX markup: comments, the preamble, math, verbatim
+// and other non-prose environments, and command names. The text arguments of
+// commands such as \section and \emph are kept.
+type latexExtractor struct {
+	opts      LaTeXOptions
+	preamble  bool   // Between \documentclass and \begin{document}
+	ended     bool   // After \end{document}
+	env       string // Environment whose contents are skipped
+	math      string // Closing delimiter of open math
+	depth     int    // Brace depth
+	dropDepth int    // Brace depth of an argument being dropped, 0 when none is
+	level     int
+	title     string
+}
+
+func (l *latexExtractor) heading() (int, string) {
+	return l.level, l.title
+}
+
+func (l *latexExtractor) line(line string) string {
+	l.level = 0
+	line = strings.TrimSuffix(line, "\r")
+
+	if l.ended {
+		return ""
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), `\documentclass`) {
+		l.preamble = true
+	}
+	if l.preamble {
+		start := strings.Index(line, `\begin{document}`)
+		if start < 0 {
+			return ""
+		}
+		l.preamble = false
+		line = line[start+len(`\begin{document}`):]
+	}
+
+	prose := l.scan(line)
+	if l.level > 0 {
+		l.title = strings.TrimSpace(prose)
+	}
+	return prose
+}
+
+// scan strips the markup from a line of the document body
+func (l *latexExtractor) scan(line string) string {
+	var prose strings.Builder
+
+	for i := 0; i < len(line); {
+		if l.env != "" {
+			end := strings.Index(line[i:], `\end{`+l.env+`}`)
+			if end < 0 {
+				break
+			}
+			i += end + len(`\end{`+l.env+`}`)
+			l.env = ""
+			continue
+		}
+
+		if l.math != "" {
+			end := indexUnescaped(line[i:], l.math)
+			if end < 0 {
+				break
+			}
+			i += end + len(l.math)
+			l.math = ""
+			continue
+		}
+
+		c := line[i]
+		if l.dropDepth > 0 {
+			switch c {
+			case '\\':
+				i++
+			case '{':
+				l.depth++
+			case '}':
+				l.depth--
+				if l.depth < l.dropDepth {
+					l.dropDepth = 0
+				}
+			}
+			i++
+			continue
+		}
+
+		switch c {
+		case '%':
+			return prose.String()
+		case '\\':
+			i = l.command(line, i+1, &prose)
+			continue
+		case '$':
+			if strings.HasPrefix(line[i:], "$$") {
+				l.math = "$$"
+				i += 2
+			} else {
+				l.math = "$"
+				i++
+			}
+			continue
+		case '{':
+			l.depth++
+		case '}':
+			if l.depth > 0 {
+				l.depth--
+			}
+		case '~', '&':
+			prose.WriteByte(' ')
+		default:
+			prose.WriteByte(c)
+		}
+		i++
+	}
+
+	return prose.String()
+}
+
+// command handles the control sequence starting at line[i], just after its
+// backslash, and returns the index after it
+func (l *latexExtractor) command(line string, i int, prose *strings.Builder) int {
+	if i >= len(line) {
+		return i
+	}
+
+	start := i
+	for i < len(line) && (line[i] >= 'a' && line[i] <= 'z' || line[i] >= 'A' && line[i] <= 'Z') {
+		i++
+	}
+	if i == start {
+		// Control symbols: escaped characters, spacing and inline math
+		switch line[i] {
+		case '%', '&', '$', '#', '_', '{', '}':
+			prose.WriteByte(line[i])
+		case '[':
+			l.math = `\]`
+		case '(':
+			l.math = `\)`
+		case '\\', ',', ';', ':', '!', ' ':
+			prose.WriteByte(' ')
+		}
+		return i + 1
+	}
+
+	name := line[start:i]
+	if i < len(line) && line[i] == '*' {
+		i++
+	}
+
+	switch {
+	case name == "verb":
+		// \verb|text| uses any character as its delimiter
+		if i < len(line) {
+			if end := strings.IndexByte(line[i+1:], line[i]); end >= 0 {
+				return i + 1 + end + 1
+			}
+		}
+		return len(line)
+
+	case name == "begin" || name == "end":
+		env, next := braceArgument(line, i)
+		i = next
+		switch {
+		case name == "end" && env == "document":
+			l.ended = true
+			return len(line)
+		case name == "begin" && (latexSkippedEnvironments[env] || env == "thebibliography" && l.opts.SkipBibliography):
+			l.env = env
+		case name == "begin" && (latexArgumentEnvironments[env] || env == "thebibliography"):
+			i = l.dropArguments(line, skipOptional(line, i), -1)
+		}
+		return skipOptional(line, i)
+
+	case latexDroppedCommands[name] || name == "caption" && l.opts.SkipCaptions:
+		return l.dropArguments(line, skipOptional(line, i), -1)
+
+	case latexFirstArgumentDropped[name]:
+		return l.dropArguments(line, skipOptional(line, i), 1)
+	}
+
+	if level, ok := latexHeadingLevels[name]; ok {
+		l.level = level
+	}
+	if name == "item" || name == "bibitem" {
+		prose.WriteByte(' ')
+		if name == "bibitem" {
+			// The citation key is not prose
+			return l.dropArguments(line, skipOptional(line, i), 1)
+		}
+	}
+
+	// The arguments of other commands are prose; their braces are dropped as
+	// the scan continues
+	return skipOptional(line, i)
+}
+
+// dropArguments skips up to count brace arguments starting at line[i], or all
+// of them when count is negative. An argument left open at the end of the
+// line is dropped on the following lines.
+func (l *latexExtractor) dropArguments(line string, i, count int) int {
+	for n := 0; count < 0 || n < count; n++ {
+		j := i
+		for j < len(line) && line[j] == ' ' {
+			j++
+		}
+		if j >= len(line) || line[j] != '{' {
+			return i
+		}
+
+		_, next := braceArgument(line, j)
+		if next > len(line) {
+			// Drop the rest of the argument on the following lines
+			l.dropDepth = l.depth + 1
+			l.depth += braceBalance(line[j:])
+			return len(line)
+		}
+		i = next
+	}
+	return i
+}
+
+// braceArgument returns the contents of the brace group at line[i] and the
+// index after it, or an index past the end of the line when it is not closed
+func braceArgument(line string, i int) (string, int) {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	if i >= len(line) || line[i] != '{' {
+		return "", i
+	}
+
+	depth := 0
+	for j := i; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return line[i+1 : j], j + 1
+			}
+		}
+	}
+	return line[i+1:], len(line) + 1
+}
+
+// braceBalance returns how many more braces s opens than it closes
+func braceBalance(s string) int {
+	balance := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			balance++
+		case '}':
+			balance--
+		}
+	}
+	return balance
+}
+
+// skipOptional skips the [optional] arguments starting at line[i]
+func skipOptional(line string, i int) int {
+	for i < len(line) && line[i] == '[' {
+		end := strings.IndexByte(line[i:], ']')
+		if end < 0 {
+			return len(line)
+		}
+		i += end + 1
+	}
+	return i
+}
+
+// indexUnescaped returns the index of the first occurrence of delimiter in s
+// that is not escaped with a backslash, or -1
+func indexUnescaped(s, delimiter string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && !strings.HasPrefix(s[i:], delimiter) {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], delimiter) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxFileSize is the size ceiling used when none is configured
+const DefaultMaxFileSize = 100 * MB
+
+// binaryPercent is the share of a text file's first bytes that may be
+// control characters or invalid UTF-8 before it is taken for binary content
+const binaryPercent = 30
+
+// Units of FileSize
+const (
+	KB FileSize = 1024
+	MB          = 1024 * KB
+	GB          = 1024 * MB
+)
+
+// FileSize is a number of bytes, written as a plain number or with a KB, MB
+// or GB suffix, e.g. 20MB
+type FileSize int64
+
+// ParseFileSize parses a size such as 500KB, 20MB or 1.5GB. A number
+// without a unit is in bytes.
+func ParseFileSize(s string) (FileSize, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	unit := FileSize(1)
+	for _, suffix := range []struct {
+		name string
+		size FileSize
+	}{{"GB", GB}, {"MB", MB}, {"KB", KB}, {"B", 1}} {
+		if strings.HasSuffix(text, suffix.name) {
+			text, unit = strings.TrimSpace(strings.TrimSuffix(text, suffix.name)), suffix.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid file size %q, want e.g. 500KB or 20MB", s)
+	}
+	return FileSize(n * float64(unit)), nil
+}
+
+// String formats the size in the largest unit that divides it
+func (s FileSize) String() string {
+	for _, unit := range []struct {
+		name string
+		size FileSize
+	}{{"GB", GB}, {"MB", MB}, {"KB", KB}} {
+		if s >= unit.size && s%unit.size == 0 {
+			return fmt.Sprintf("%d%s", s/unit.size, unit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+// Set parses a size given on the command line
+func (s *FileSize) Set(value string) error {
+	size, err := ParseFileSize(value)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// SkippedFile records a file that was left out of the count because it
+// looked like binary content or was too large
+type SkippedFile struct {
+	Path   string // Slash-separated path relative to the project
+	Reason string
+}
+
+// skipError is returned by an extractor for a file that should be skipped
+// rather than reported as unreadable
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+// looksBinary reports whether the first bytes of a text file hold NUL bytes,
+// or so many control characters and bytes that are not UTF-8 that the file
+// cannot be text in any encoding that is read
+func looksBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	suspicious := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			if !utf8.FullRune(head[i:]) {
+				// A character cut off at the end of the sample
+				i = len(head)
+				continue
+			}
+			suspicious++
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f':
+			suspicious++
+		}
+		i += size
+	}
+
+	return suspicious*100 > len(head)*binaryPercent
+}
@@ -0,0 +1,306 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MarkdownOptions controls which Markdown constructs count as prose. The zero
+// value counts only readable text: code, image alt text and HTML comments are
+// excluded, and markup such as heading hashes, emphasis markers, link URLs and
+// table pipes is always removed.
+type MarkdownOptions struct {
+	Raw               bool // Count the raw Markdown source, as before extraction existed
+	CountCodeBlocks   bool // Count the contents of fenced code blocks
+	CountInlineCode   bool // Count the contents of `inline code` spans
+	CountAltText      bool // Count image alt text
+	CountHTMLComments bool // Count the text inside <!-- comments -->
+	SkipTables        bool // Leave table cells out of the count
+}
+
+// Mode names how these options extract Markdown, so a change that alters a
+// project's count can be told from words written: raw, or prose followed by
+// the constructs counted or skipped beyond the defaults, e.g. prose+code_blocks
+func (o MarkdownOptions) Mode() string {
+	if o.Raw {
+		return "raw"
+	}
+
+	mode := "prose"
+	for _, option := range []struct {
+		set  bool
+		name string
+	}{
+		{o.CountCodeBlocks, "code_blocks"},
+		{o.CountInlineCode, "inline_code"},
+		{o.CountAltText, "alt_text"},
+		{o.CountHTMLComments, "html_comments"},
+		{o.SkipTables, "no_tables"},
+	} {
+		if option.set {
+			mode += "+" + option.name
+		}
+	}
+	return mode
+}
+
+var (
+	headingPattern      = regexp.MustCompile(`^\s{0,3}#{1,6}(\s+|$)`)
+	closingHashPattern  = regexp.MustCompile(`\s+#+\s*$`)
+	setextPattern       = regexp.MustCompile(`^\s{0,3}(?:=+|-+)\s*$`)
+	rulePattern         = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,}|=+\s*)$`)
+	blockquotePattern   = regexp.MustCompile(`^\s*(?:>\s?)+`)
+	listItemPattern     = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+	linkDefPattern      = regexp.MustCompile(`^\s{0,3}\[[^\]^][^\]]*\]:\s*\S+`)
+	footnoteDefPattern  = regexp.MustCompile(`^\s{0,3}\[\^[^\]]+\]:\s*`)
+	tableDividerPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	imagePattern        = regexp.MustCompile(`!\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	linkPattern         = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	footnoteRefPattern  = regexp.MustCompile(`\[\^[^\]]+\]`)
+	autolinkPattern     = regexp.MustCompile(`<(?:[a-zA-Z][a-zA-Z0-9+.-]*:|[^\s@<>]+@)[^\s<>]*>`)
+	htmlTagPattern      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	emphasisPattern     = regexp.MustCompile(`\*+|~~`)
+	leadingUnderscores  = regexp.MustCompile(`(^|[^\p{L}\p{N}])_+`)
+	trailingUnderscores = regexp.MustCompile(`_+([^\p{L}\p{N}]|$)`)
+	escapedPunctPattern = regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`)
+)
+
+// markdownExtractor turns Markdown into readable prose one line at a time,
+// carrying code fence, comment and table state between lines
+type markdownExtractor struct {
+	opts      MarkdownOptions
+	fence     string // Opening code fence while inside a fenced block
+	inComment bool   // Inside a multi-line HTML comment
+	inTable   bool   // Inside a table after its divider row
+	level     int    // Level of the heading on the last line, 0 if it was not a heading
+	title     string // Prose of the heading on the last line
+	underline bool   // The last line was a setext underline, with the heading's title on the line before
+	paragraph string // Prose of the last line when it was paragraph text, which an underline makes a heading
+}
+
+// line returns the prose in a single line of Markdown, or the line itself
+// when raw Markdown is counted. Code fences and headings are tracked either way.
+func (m *markdownExtractor) line(line string) string {
+	prose := m.extract(line)
+	if m.opts.Raw {
+		return line
+	}
+	return prose
+}
+
+func (m *markdownExtractor) heading() (int, string) {
+	return m.level, m.title
+}
+
+func (m *markdownExtractor) underlined() bool {
+	return m.underline
+}
+
+// skipLine records that a line was dropped before reaching the extractor, so
+// it is neither a heading nor the title of one
+func (m *markdownExtractor) skipLine() {
+	m.level, m.underline, m.paragraph = 0, false, ""
+}
+
+// code returns the byte ranges of line that are code: the whole line inside
+// a fenced code block or on one of its fences, and its code spans otherwise
+func (m *markdownExtractor) code(line string) [][2]int {
+	if m.fence != "" || openingFence(strings.TrimSpace(line)) != "" {
+		return [][2]int{{0, len(line)}}
+	}
+	return codeSpans(line)
+}
+
+// extract returns the prose in a single line of Markdown
+func (m *markdownExtractor) extract(line string) string {
+	paragraph := m.paragraph
+	m.skipLine()
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+
+	if m.fence != "" {
+		if isClosingFence(trimmed, m.fence) {
+			m.fence = ""
+			return ""
+		}
+		if m.opts.CountCodeBlocks {
+			return line
+		}
+		return ""
+	}
+
+	line = m.stripComments(line)
+	trimmed = strings.TrimSpace(line)
+
+	if fence := openingFence(trimmed); fence != "" {
+		m.fence = fence
+		return ""
+	}
+
+	if trimmed == "" {
+		m.inTable = false
+		return ""
+	}
+
+	if tableDividerPattern.MatchString(trimmed) && strings.Contains(trimmed, "|") {
+		m.inTable = true
+		return ""
+	}
+
+	if paragraph != "" && setextPattern.MatchString(line) {
+		// A line of = or - under paragraph text makes it a level 1 or 2 heading
+		m.level, m.title, m.underline = 1, paragraph, true
+		if strings.HasPrefix(trimmed, "-") {
+			m.level = 2
+		}
+		return ""
+	}
+
+	if rulePattern.MatchString(line) || linkDefPattern.MatchString(line) {
+		return ""
+	}
+
+	table := m.inTable || strings.HasPrefix(trimmed, "|")
+	if table {
+		if m.opts.SkipTables {
+			return ""
+		}
+		line = strings.ReplaceAll(line, "|", " ")
+	}
+
+	if headingPattern.MatchString(line) {
+		m.level = len(strings.TrimLeft(line, " ")) - len(strings.TrimLeft(strings.TrimLeft(line, " "), "#"))
+		line = headingPattern.ReplaceAllString(line, "")
+		line = closingHashPattern.ReplaceAllString(line, "")
+	}
+
+	marked := blockquotePattern.MatchString(line) || listItemPattern.MatchString(line) || footnoteDefPattern.MatchString(line)
+	line = blockquotePattern.ReplaceAllString(line, "")
+	line = listItemPattern.ReplaceAllString(line, "")
+	line = footnoteDefPattern.ReplaceAllString(line, "")
+
+	prose := m.stripInline(line)
+	switch {
+	case m.level > 0:
+		m.title = prose
+	case !table && !marked && strings.TrimSpace(prose) != "":
+		m.paragraph = prose
+	}
+	return prose
+}
+
+// stripComments removes HTML comments from line, tracking comments that span lines
+func (m *markdownExtractor) stripComments(line string) string {
+	var out strings.Builder
+
+	for line != "" {
+		if m.inComment {
+			end := strings.Index(line, "-->")
+			if end < 0 {
+				if m.opts.CountHTMLComments {
+					out.WriteString(line)
+				}
+				return out.String()
+			}
+			if m.opts.CountHTMLComments {
+				out.WriteString(line[:end])
+			}
+			line = line[end+3:]
+			m.inComment = false
+			continue
+		}
+
+		start := strings.Index(line, "<!--")
+		if start < 0 {
+			out.WriteString(line)
+			break
+		}
+		out.WriteString(line[:start])
+		line = line[start+4:]
+		m.inComment = true
+	}
+
+	return out.String()
+}
+
+// stripInline removes inline Markdown syntax, keeping the readable text
+func (m *markdownExtractor) stripInline(line string) string {
+	line = stripCodeSpans(line, m.opts.CountInlineCode)
+
+	if m.opts.CountAltText {
+		line = imagePattern.ReplaceAllString(line, "$1")
+	} else {
+		line = imagePattern.ReplaceAllString(line, "")
+	}
+
+	line = footnoteRefPattern.ReplaceAllString(line, "")
+	line = linkPattern.ReplaceAllString(line, "$1")
+	line = autolinkPattern.ReplaceAllString(line, "")
+	line = htmlTagPattern.ReplaceAllString(line, "")
+	line = emphasisPattern.ReplaceAllString(line, "")
+	line = leadingUnderscores.ReplaceAllString(line, "$1")
+	line = trailingUnderscores.ReplaceAllString(line, "$1")
+	line = escapedPunctPattern.ReplaceAllString(line, "$1")
+
+	return line
+}
+
+// stripCodeSpans removes `code spans` from line, keeping their contents when keep is set
+func stripCodeSpans(line string, keep bool) string {
+	var out strings.Builder
+
+	pos := 0
+	for _, span := range codeSpans(line) {
+		out.WriteString(line[pos:span[0]])
+		if keep {
+			code := line[span[0]:span[1]]
+			ticks := len(code) - len(strings.TrimLeft(code, "`"))
+			out.WriteString(code[ticks : len(code)-ticks])
+		}
+		pos = span[1]
+	}
+	out.WriteString(line[pos:])
+
+	return out.String()
+}
+
+// codeSpans returns the byte ranges of the `code spans` in line, backticks included
+func codeSpans(line string) [][2]int {
+	var spans [][2]int
+
+	for pos := 0; ; {
+		start := strings.Index(line[pos:], "`")
+		if start < 0 {
+			return spans
+		}
+		start += pos
+
+		ticks := len(line[start:]) - len(strings.TrimLeft(line[start:], "`"))
+		delimiter := line[start : start+ticks]
+
+		end := strings.Index(line[start+ticks:], delimiter)
+		if end < 0 {
+			// Unmatched backticks are literal text
+			pos = start + ticks
+			continue
+		}
+
+		pos = start + ticks + end + ticks
+		spans = append(spans, [2]int{start, pos})
+	}
+}
+
+// openingFence returns the fence marker if line opens a fenced code block
+func openingFence(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return line[:len(line)-len(strings.TrimLeft(line, marker[:1]))]
+		}
+	}
+	return ""
+}
+
+// isClosingFence reports whether line closes the block opened by fence
+func isClosingFence(line, fence string) bool {
+	return strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == ""
+}
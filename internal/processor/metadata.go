@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// metadataTables are the frontmatter tables searched for verkount's keys
+// after the top level, where Hugo and Zola keep custom page variables
+var metadataTables = []string{"params", "extra"}
+
+// Breakdown holds a project's words per value of the status, pov and tags
+// frontmatter keys. A file with several tags counts towards each of them.
+type Breakdown struct {
+	Status map[string]int // Words per revision status, e.g. draft, revised or final
+	POV    map[string]int // Words per point-of-view character
+	Tags   map[string]int // Words per tag
+}
+
+// newBreakdown returns an empty Breakdown
+func newBreakdown() *Breakdown {
+	return &Breakdown{
+		Status: make(map[string]int),
+		POV:    make(map[string]int),
+		Tags:   make(map[string]int),
+	}
+}
+
+// add counts a file's words towards the dimensions its metadata names,
+// reporting whether it named any
+func (b *Breakdown) add(metadata map[string]any, words int) bool {
+	found := false
+	for _, dimension := range []struct {
+		key    string
+		counts map[string]int
+	}{
+		{"status", b.Status},
+		{"pov", b.POV},
+		{"tags", b.Tags},
+	} {
+		for _, value := range metadataValues(metadata, dimension.key) {
+			dimension.counts[value] += words
+			found = true
+		}
+	}
+	return found
+}
+
+// excludedByMetadata reports whether a file's metadata takes it out of the
+// count with verkount: false
+func excludedByMetadata(metadata map[string]any) bool {
+	switch value := metadataValue(metadata, "verkount").(type) {
+	case bool:
+		return !value
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "false", "no", "off":
+			return true
+		}
+	}
+	return false
+}
+
+// metadataValue returns the value of key at the top level of metadata or in
+// one of the metadataTables, or nil
+func metadataValue(metadata map[string]any, key string) any {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+	for _, name := range metadataTables {
+		if table, ok := metadata[name].(map[string]any); ok {
+			if value, ok := table[key]; ok {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// metadataValues returns the distinct values of key, which may be a single
+// value or a list. Tags may also be given as a comma-separated string.
+func metadataValues(metadata map[string]any, key string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	addValue := func(v any) {
+		if v == nil {
+			return
+		}
+		text := strings.TrimSpace(fmt.Sprint(v))
+		if text != "" && !seen[text] {
+			seen[text] = true
+			values = append(values, text)
+		}
+	}
+
+	switch value := metadataValue(metadata, key).(type) {
+	case []any:
+		for _, v := range value {
+			addValue(v)
+		}
+	case string:
+		if key != "tags" {
+			addValue(value)
+			break
+		}
+		for _, v := range strings.Split(value, ",") {
+			addValue(v)
+		}
+	default:
+		addValue(value)
+	}
+
+	return values
+}
@@ -0,0 +1,173 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	chunkFencePattern = regexp.MustCompile("^(`{3,}|~{3,})\\s*\\{[^}]*\\}")
+	quartoDivPattern  = regexp.MustCompile(`^:{3,}`)
+)
+
+// literateFormat extracts prose from Quarto (.qmd) and R Markdown (.Rmd)
+// documents: Markdown with YAML frontmatter and executable code chunks
+type literateFormat struct {
+	name string
+}
+
+func (f literateFormat) Name() string { return f.name }
+
+func (f literateFormat) Extract(path string, opts Options, doc *Document) error {
+	newMarkup := func() markupStripper {
+		return &chunkStripper{markdown: &markdownExtractor{opts: opts.Markdown}}
+	}
+	return extractText(path, doc, newMarkup, true, opts.Markdown.Raw)
+}
+
+// chunkStripper drops executable code chunks such as ```{r} and ```{python},
+// whatever the Markdown options say about code blocks, and Quarto ::: div
+// fences, passing the rest to the Markdown extractor
+type chunkStripper struct {
+	markdown *markdownExtractor
+	fence    string // Opening fence of the chunk being skipped
+}
+
+func (c *chunkStripper) line(line string) string {
+	trimmed := strings.TrimSpace(line)
+
+	if c.fence != "" {
+		if isClosingFence(trimmed, c.fence) {
+			c.fence = ""
+		}
+		c.markdown.skipLine()
+		return ""
+	}
+
+	if c.markdown.fence == "" {
+		if m := chunkFencePattern.FindStringSubmatch(trimmed); m != nil {
+			c.fence = m[1]
+			c.markdown.skipLine()
+			return ""
+		}
+		if quartoDivPattern.MatchString(trimmed) && !c.markdown.opts.Raw {
+			c.markdown.skipLine()
+			return ""
+		}
+	}
+
+	return c.markdown.line(line)
+}
+
+func (c *chunkStripper) heading() (int, string) {
+	return c.markdown.heading()
+}
+
+func (c *chunkStripper) underlined() bool {
+	return c.markdown.underlined()
+}
+
+// notebookCell is the part of a Jupyter notebook cell that holds prose
+type notebookCell struct {
+	CellType string          `json:"cell_type"`
+	Source   json.RawMessage `json:"source"`
+}
+
+// notebookFormat extracts prose from the Markdown cells of Jupyter notebooks
+type notebookFormat struct{}
+
+func (notebookFormat) Name() string { return "jupyter" }
+
+func (notebookFormat) Extract(path string, opts Options, doc *Document) error {
+	file, err := openText(path, doc)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Cells are decoded one at a time, so a notebook's code cells and their
+	// outputs are never held in memory together
+	decoder := json.NewDecoder(file)
+	if err := seekNotebookCells(decoder); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for decoder.More() {
+		var cell notebookCell
+		if err := decoder.Decode(&cell); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if cell.CellType != "markdown" {
+			continue
+		}
+
+		source, err := cellSource(cell.Source)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		// Each cell is a Markdown document of its own
+		stream := &textStream{
+			doc:      doc,
+			markup:   &markdownExtractor{opts: opts.Markdown},
+			excluded: &markdownExtractor{opts: opts.Markdown},
+		}
+		for _, line := range strings.Split(source, "\n") {
+			stream.line(line, false)
+		}
+		stream.close()
+	}
+
+	return nil
+}
+
+// seekNotebookCells advances decoder to the first element of the notebook's
+// top level "cells" array
+func seekNotebookCells(decoder *json.Decoder) error {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("not a Jupyter notebook")
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if key == "cells" {
+			token, err := decoder.Token()
+			if err != nil || token != json.Delim('[') {
+				return fmt.Errorf("notebook cells are not a list")
+			}
+			return nil
+		}
+
+		// Skip the value of any other key
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("notebook has no cells")
+}
+
+// cellSource returns the text of a cell, which notebooks store either as a
+// single string or as a list of lines
+func cellSource(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err != nil {
+		return "", fmt.Errorf("unexpected cell source: %v", err)
+	}
+	return strings.Join(lines, ""), nil
+}
@@ -0,0 +1,330 @@
+package processor
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OfficeOptions controls which parts of word processor documents count as
+// prose. The zero value counts only the body text: tracked deletions and
+// comments are never counted, and headers and footers are left out.
+type OfficeOptions struct {
+	HeadersFooters bool // Count page headers and footers
+}
+
+const (
+	wordNamespace   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	compatNamespace = "http://schemas.openxmlformats.org/markup-compatibility/2006"
+	odfText         = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odfOffice       = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odfStyle        = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+)
+
+var (
+	wordHeadingStyle = regexp.MustCompile(`^(?i:heading)\s*(\d)$`)
+	wordHeaderPart   = regexp.MustCompile(`^word/(?:header|footer)\d*\.xml$`)
+)
+
+// paragraph is the text gathered so far for one paragraph of a document
+type paragraph struct {
+	text  strings.Builder
+	level int // Heading level, 0 for body text
+}
+
+// paragraphStack collects the paragraphs of an XML document part. Paragraphs
+// may nest, as text boxes and notes do, so each is written to the Document
+// when it closes.
+type paragraphStack []*paragraph
+
+func (s *paragraphStack) open() {
+	*s = append(*s, &paragraph{})
+}
+
+func (s paragraphStack) current() *paragraph {
+	if len(s) == 0 {
+		return nil
+	}
+	return s[len(s)-1]
+}
+
+func (s paragraphStack) write(text string) {
+	if p := s.current(); p != nil {
+		p.text.WriteString(text)
+	}
+}
+
+func (s *paragraphStack) close(doc *Document) {
+	p := s.current()
+	if p == nil {
+		return
+	}
+	*s = (*s)[:len(*s)-1]
+
+	if p.level > 0 {
+		doc.Heading(p.level, p.text.String())
+	} else {
+		doc.Text(p.text.String())
+	}
+}
+
+// zipParts returns the files of a zip archive by name
+func zipParts(archive *zip.ReadCloser) map[string]*zip.File {
+	parts := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+	return parts
+}
+
+// readPart streams one file of a zip archive through fn
+func readPart(f *zip.File, fn func(r io.Reader) error) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := fn(r); err != nil {
+		return fmt.Errorf("%s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// docxFormat extracts prose from Office Open XML (.docx) documents
+type docxFormat struct{}
+
+func (docxFormat) Name() string { return "docx" }
+
+func (docxFormat) Extract(filePath string, opts Options, doc *Document) error {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	parts := zipParts(archive)
+	body, ok := parts["word/document.xml"]
+	if !ok {
+		return fmt.Errorf("%s is not a Word document", filePath)
+	}
+
+	if opts.Office.HeadersFooters {
+		var names []string
+		for name := range parts {
+			if wordHeaderPart.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		// Headers and footers come before the body, so they are not counted
+		// as part of its last chapter
+		for _, name := range names {
+			if err := readPart(parts[name], func(r io.Reader) error { return extractWordXML(r, doc) }); err != nil {
+				return err
+			}
+		}
+	}
+
+	return readPart(body, func(r io.Reader) error { return extractWordXML(r, doc) })
+}
+
+// extractWordXML writes the paragraphs of a WordprocessingML part to doc,
+// leaving out tracked deletions and the fallback copies of text boxes
+func extractWordXML(r io.Reader, doc *Document) error {
+	decoder := xml.NewDecoder(r)
+	var paragraphs paragraphStack
+	inText := false
+	skipDepth := 0 // Depth inside an element that is left out
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			if t.Name.Space == compatNamespace && t.Name.Local == "Fallback" {
+				skipDepth = 1
+				continue
+			}
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "del", "moveFrom":
+				skipDepth = 1
+			case "p":
+				paragraphs.open()
+			case "pStyle":
+				if m := wordHeadingStyle.FindStringSubmatch(attr(t, wordNamespace, "val")); m != nil && paragraphs.current() != nil {
+					paragraphs.current().level, _ = strconv.Atoi(m[1])
+				}
+			case "outlineLvl":
+				if level, err := strconv.Atoi(attr(t, wordNamespace, "val")); err == nil && level < 9 && paragraphs.current() != nil {
+					paragraphs.current().level = level + 1
+				}
+			case "t":
+				inText = true
+			case "tab", "br", "cr":
+				paragraphs.write(" ")
+			}
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				paragraphs.close(doc)
+			}
+
+		case xml.CharData:
+			if skipDepth == 0 && inText {
+				paragraphs.write(string(t))
+			}
+		}
+	}
+}
+
+// odtFormat extracts prose from OpenDocument text (.odt) documents
+type odtFormat struct{}
+
+func (odtFormat) Name() string { return "odt" }
+
+func (odtFormat) Extract(filePath string, opts Options, doc *Document) error {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	parts := zipParts(archive)
+	content, ok := parts["content.xml"]
+	if !ok {
+		return fmt.Errorf("%s is not an OpenDocument text", filePath)
+	}
+
+	if styles, ok := parts["styles.xml"]; ok && opts.Office.HeadersFooters {
+		// Headers and footers are defined with the master pages in styles.xml
+		err := readPart(styles, func(r io.Reader) error {
+			return extractODFXML(r, doc, func(name xml.Name) bool {
+				return name.Space == odfStyle && (strings.HasPrefix(name.Local, "header") || strings.HasPrefix(name.Local, "footer"))
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return readPart(content, func(r io.Reader) error {
+		return extractODFXML(r, doc, func(name xml.Name) bool {
+			return name.Space == odfOffice && name.Local == "body"
+		})
+	})
+}
+
+// extractODFXML writes the paragraphs and headings found inside elements
+// matching within to doc, leaving out annotations and tracked changes
+func extractODFXML(r io.Reader, doc *Document, within func(xml.Name) bool) error {
+	decoder := xml.NewDecoder(r)
+	var paragraphs paragraphStack
+	withinDepth := 0 // Depth inside an element that holds prose
+	skipDepth := 0   // Depth inside an element that is left out
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if withinDepth == 0 {
+				if within(t.Name) {
+					withinDepth = 1
+				}
+				continue
+			}
+			withinDepth++
+
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+
+			switch {
+			case t.Name.Space == odfOffice && t.Name.Local == "annotation",
+				t.Name.Space == odfText && (t.Name.Local == "tracked-changes" || t.Name.Local == "note-citation"):
+				// Comments, deleted text and footnote numbers
+				skipDepth = 1
+			case t.Name.Space != odfText:
+			case t.Name.Local == "p":
+				paragraphs.open()
+			case t.Name.Local == "h":
+				paragraphs.open()
+				level, err := strconv.Atoi(attr(t, odfText, "outline-level"))
+				if err != nil || level < 1 {
+					level = 1
+				}
+				paragraphs.current().level = level
+			case t.Name.Local == "s", t.Name.Local == "tab", t.Name.Local == "line-break":
+				paragraphs.write(" ")
+			}
+
+		case xml.EndElement:
+			if withinDepth == 0 {
+				continue
+			}
+			withinDepth--
+
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if t.Name.Space == odfText && (t.Name.Local == "p" || t.Name.Local == "h") {
+				paragraphs.close(doc)
+			}
+
+		case xml.CharData:
+			if withinDepth > 0 && skipDepth == 0 {
+				paragraphs.write(string(t))
+			}
+		}
+	}
+}
+
+// attr returns the value of an element's attribute, or "" if it is not set
+func attr(element xml.StartElement, space, local string) string {
+	for _, a := range element.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	orgHeadlinePattern = regexp.MustCompile(`^(\*+)\s+(.*)$`)
+	orgKeywordPattern  = regexp.MustCompile(`^(?:TODO|DONE|NEXT|WAITING|HOLD|CANCELLED|CANCELED)\s+`)
+	orgPriorityPattern = regexp.MustCompile(`^\[#[A-Za-z0-9]\]\s*`)
+	orgTagsPattern     = regexp.MustCompile(`\s+:[\w@#%:]+:\s*$`)
+	orgDrawerPattern   = regexp.MustCompile(`^\s*:[\w-]+:\s*$`)
+	orgPlanningPattern = regexp.MustCompile(`^\s*(?:SCHEDULED|DEADLINE|CLOSED):`)
+	orgKeywordLine     = regexp.MustCompile(`^\s*#\+\w+:`)
+	orgBlockPattern    = regexp.MustCompile(`(?i)^\s*#\+(begin|end)_(\w+)`)
+	orgListPattern     = regexp.MustCompile(`^\s*(?:[-+]|\d+[.)])\s+(?:\[[ xX-]\]\s+)?`)
+	orgTableRule       = regexp.MustCompile(`^\s*\|[-+|:\s]*$`)
+	orgLinkPattern     = regexp.MustCompile(`\[\[([^\]]*)\](?:\[([^\]]*)\])?\]`)
+	orgFootnotePattern = regexp.MustCompile(`\[fn:[^\]]*\]`)
+	orgOpenEmphasis    = regexp.MustCompile(`(^|[\s(\-"'{])[*/_=~+]+(\S)`)
+	orgCloseEmphasis   = regexp.MustCompile(`(\S)[*/_=~+]+([\s\-.,;:!?'")}\]]|$)`)
+)
+
+// orgBlocksWithoutProse are the #+begin_ blocks whose contents are not counted
+var orgBlocksWithoutProse = map[string]bool{
+	"src": true, "example": true, "comment": true, "export": true, "latex": true, "html": true,
+}
+
+// orgExtractor strips Org mode markup: headline stars, TODO keywords, tags,
+// drawers such as :PROPERTIES:, planning lines, #+ keywords, source and
+// comment blocks, links, footnotes and emphasis markers
+type orgExtractor struct {
+	inDrawer bool   // Inside a :DRAWER: ... :END: block
+	block    string // Name of an open #+begin_ block without prose
+	level    int
+	title    string
+}
+
+func (o *orgExtractor) heading() (int, string) {
+	return o.level, o.title
+}
+
+func (o *orgExtractor) line(line string) string {
+	o.level = 0
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+
+	if o.block != "" {
+		if m := orgBlockPattern.FindStringSubmatch(line); m != nil && strings.EqualFold(m[1], "end") && strings.EqualFold(m[2], o.block) {
+			o.block = ""
+		}
+		return ""
+	}
+
+	if o.inDrawer {
+		if strings.EqualFold(trimmed, ":END:") {
+			o.inDrawer = false
+		}
+		return ""
+	}
+
+	if m := orgBlockPattern.FindStringSubmatch(line); m != nil {
+		if strings.EqualFold(m[1], "begin") && orgBlocksWithoutProse[strings.ToLower(m[2])] {
+			o.block = m[2]
+		}
+		// Delimiters of quote, verse and other prose blocks are dropped
+		return ""
+	}
+
+	if orgDrawerPattern.MatchString(line) && !strings.EqualFold(trimmed, ":END:") {
+		o.inDrawer = true
+		return ""
+	}
+
+	if trimmed == "#" || strings.HasPrefix(trimmed, "# ") || orgKeywordLine.MatchString(line) ||
+		orgPlanningPattern.MatchString(line) || orgTableRule.MatchString(line) && strings.HasPrefix(trimmed, "|") {
+		return ""
+	}
+
+	if m := orgHeadlinePattern.FindStringSubmatch(line); m != nil {
+		o.level = len(m[1])
+		line = orgKeywordPattern.ReplaceAllString(m[2], "")
+		line = orgPriorityPattern.ReplaceAllString(line, "")
+		line = orgTagsPattern.ReplaceAllString(line, "")
+		line = stripOrgInline(line)
+		o.title = line
+		return line
+	}
+
+	if strings.HasPrefix(trimmed, "|") {
+		line = strings.ReplaceAll(line, "|", " ")
+	}
+	line = orgListPattern.ReplaceAllString(line, "")
+
+	return stripOrgInline(line)
+}
+
+// stripOrgInline removes Org links, footnote references and emphasis markers
+func stripOrgInline(line string) string {
+	line = orgFootnotePattern.ReplaceAllString(line, "")
+	line = orgLinkPattern.ReplaceAllStringFunc(line, func(link string) string {
+		m := orgLinkPattern.FindStringSubmatch(link)
+		if m[2] != "" {
+			return m[2]
+		}
+		if strings.Contains(m[1], ":") {
+			// Bare URLs and file links are not prose
+			return ""
+		}
+		return m[1]
+	})
+	line = orgOpenEmphasis.ReplaceAllString(line, "$1$2")
+	line = orgCloseEmphasis.ReplaceAllString(line, "$1$2")
+	return line
+}
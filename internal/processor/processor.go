@@ -1,85 +1,270 @@
 package processor
 
 import (
-	"bytes"
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
+
+	"github.com/bwilson/verkounter/internal/counter"
+	"github.com/bwilson/verkounter/internal/ignore"
 )
 
-func ProcessMarkdownFiles(folderPath string) (string, error) {
-	var allContent strings.Builder
+// Options controls how a project's files are turned into countable text
+type Options struct {
+	Markdown     MarkdownOptions
+	Office       OfficeOptions
+	LaTeX        LaTeXOptions
+	Counter      counter.Counter // Strategy the project's text is streamed through
+	ChapterLevel int             // Report sections split at headings up to this level, 0 to disable
+	Extensions   []string        // File extensions to count, DefaultExtensions when empty
+	MaxFileSize  FileSize        // Files larger than this are skipped, 0 for no limit
+	Include      []string        // Globs of the files to count, all files when empty
+	Exclude      []string        // Globs of the files and folders to leave out, as in .verkountignore
+	Categories   Categories      // Parts of the project counted apart, e.g. notes and research
+}
 
-	err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
+// Result holds the counts gathered from a project
+type Result struct {
+	Words      int                       // Words that count towards the project
+	Excluded   int                       // Words hidden by exclusion markers
+	Files      map[string]int            // Words per file, keyed by slash-separated path relative to the project
+	Categories map[string]int            // Words per category; without categories every word is in DefaultCategory
+	Sections   []Section                 // Words per chapter or section, in reading order, when ChapterLevel is set
+	Metadata   map[string]map[string]any // Frontmatter of each file that has any, keyed like Files
 
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".md") {
-			content, err := processMarkdownFile(path)
-			if err != nil {
+	Screenplay *Screenplay // Screenplay metrics, when the project has Fountain files
+	Breakdown  *Breakdown  // Words per status, POV and tag, when files name them in their frontmatter
+
+	Errors    []FileError             // Files and folders that could not be read, in walk order
+	Encodings map[string]FileEncoding // Text files that were not plain UTF-8, keyed like Files
+	Skipped   []SkippedFile           // Files left out as binary content or for their size, in walk order
+}
+
+// FileError records a file or folder of a project that could not be read.
+// Words a file yielded before its error still count.
+type FileError struct {
+	Path string // Slash-separated path relative to the project
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// ProcessMarkdownFiles streams every file in folderPath with an enabled
+// extension through its format's extractor and the counting strategy. Files
+// are read line by line, so memory use does not grow with the size of the
+// project.
+func ProcessMarkdownFiles(folderPath string, opts Options) (Result, error) {
+	enabled, err := enabledExtractors(opts.Extensions)
+	if err != nil {
+		return Result{}, err
+	}
+	rules, err := ignore.Load(folderPath, opts.Include, opts.Exclude)
+	if err != nil {
+		return Result{}, err
+	}
+	categoryOf, err := newCategoryMatcher(opts.Categories)
+	if err != nil {
+		return Result{}, err
+	}
+
+	prose := opts.Counter.NewTally()
+	excluded := opts.Counter.NewTally()
+	files := make(map[string]int)
+	categories := make(map[string]int)
+	metadata := make(map[string]map[string]any)
+	breakdown, hasBreakdown := newBreakdown(), false
+	var fileErrors []FileError
+	encodings := make(map[string]FileEncoding)
+	var skipped []SkippedFile
+
+	var chapters *sectionTracker
+	if opts.ChapterLevel > 0 {
+		chapters = &sectionTracker{counter: opts.Counter, level: opts.ChapterLevel}
+	}
+	screenplay := newScreenplayTracker(opts.Counter)
+
+	// count streams one document through its extractor, recording its words under name
+	count := func(name, path string, extractor Extractor) error {
+		if opts.MaxFileSize > 0 {
+			if info, err := os.Stat(path); err == nil && FileSize(info.Size()) > opts.MaxFileSize {
+				skipped = append(skipped, SkippedFile{Path: name, Reason: "larger than " + opts.MaxFileSize.String()})
 				return nil
 			}
-			allContent.WriteString(content)
-			allContent.WriteString(" ")
 		}
 
+		fileTally := opts.Counter.NewTally()
+		writers := []io.Writer{prose, fileTally}
+		var headings func(level int, text string, written bool)
+		if chapters != nil {
+			chapters.startFile(name)
+			writers = append(writers, chapters)
+			headings = chapters.heading
+		}
+
+		doc := newDocument(io.MultiWriter(writers...), excluded, headings)
+		doc.screenplay = screenplay
+		err := extractor.Extract(path, opts, doc)
+		prose.WriteString(" ")
+		excluded.WriteString(" ")
+		if skip, ok := err.(skipError); ok {
+			skipped = append(skipped, SkippedFile{Path: name, Reason: skip.reason})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if doc.metadata != nil {
+			metadata[name] = doc.metadata
+		}
+		if doc.encoding.Name != "" {
+			encodings[name] = doc.encoding
+		}
+		if doc.skipped {
+			// verkount: false in the frontmatter
+			return nil
+		}
+
+		files[name] = fileTally.Count()
+		categories[categoryOf.category(name)] += files[name]
+		if breakdown.add(doc.metadata, files[name]) {
+			hasBreakdown = true
+		}
 		return nil
-	})
+	}
 
-	if err != nil {
-		return "", err
+	report := func(fileErr FileError) {
+		fileErrors = append(fileErrors, fileErr)
+	}
+	if err := walkProject(folderPath, enabled, rules, count, report); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Words:     prose.Count(),
+		Excluded:  excluded.Count(),
+		Files:     files,
+		Metadata:  metadata,
+		Errors:    fileErrors,
+		Encodings: encodings,
+		Skipped:   skipped,
 	}
 
-	return allContent.String(), nil
+	if len(opts.Categories) == 0 {
+		// Per-file counts need not add up to the project's count
+		categories = map[string]int{DefaultCategory: result.Words}
+	}
+	result.Categories = categories
+
+	if chapters != nil {
+		chapters.finish(0)
+		result.Sections = chapters.sections
+	}
+
+	if hasBreakdown {
+		result.Breakdown = breakdown
+	}
+
+	if screenplay.used {
+		metrics := screenplay.result()
+		result.Screenplay = &metrics
+	}
+
+	return result, nil
 }
 
-func processMarkdownFile(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+// ListFiles returns the name of every document in folderPath that
+// ProcessMarkdownFiles would count, in walk order, along with the paths that
+// could not be read. Files that turn out to be binary or too large are
+// still listed.
+func ListFiles(folderPath string, opts Options) ([]string, []FileError, error) {
+	enabled, err := enabledExtractors(opts.Extensions)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := ignore.Load(folderPath, opts.Include, opts.Exclude)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	defer file.Close()
 
-	content, err := io.ReadAll(file)
+	var names []string
+	var fileErrors []FileError
+	err = walkProject(folderPath, enabled, rules, func(name, path string, extractor Extractor) error {
+		names = append(names, name)
+		return nil
+	}, func(fileErr FileError) {
+		fileErrors = append(fileErrors, fileErr)
+	})
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	return stripFrontmatter(string(content)), nil
+	return names, fileErrors, nil
 }
 
-func stripFrontmatter(content string) string {
-	lines := strings.Split(content, "\n")
-
-	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
-		inFrontmatter := true
-		startIdx := 1
+// walkProject calls visit with every document of the project in folderPath
+// that an enabled extractor reads and the ignore rules leave in, under its
+// slash-separated name in the project. Each document of a bundle is visited
+// on its own. Paths that cannot be read, and visits that fail, are passed to
+// report and the walk goes on.
+func walkProject(folderPath string, enabled map[string]Extractor, rules *ignore.Rules,
+	visit func(name, path string, extractor Extractor) error, report func(FileError)) error {
+	return filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
+		relPath, relErr := filepath.Rel(folderPath, path)
+		if relErr != nil {
+			relPath = path
+		}
+		relPath = filepath.ToSlash(relPath)
 
-		for i := 1; i < len(lines); i++ {
-			if strings.TrimSpace(lines[i]) == "---" {
-				inFrontmatter = false
-				startIdx = i + 1
-				break
+		if rules.Ignored(relPath, info != nil && info.IsDir()) {
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		if !inFrontmatter && startIdx < len(lines) {
-			return strings.Join(lines[startIdx:], "\n")
+		if err != nil {
+			// Unreadable files and folders are reported, and the walk goes on
+			report(FileError{Path: relPath, Err: err})
+			return nil
 		}
-	}
 
-	content = strings.TrimSpace(content)
+		extractor, ok := enabled[NormalizeExtension(filepath.Ext(path))]
+		if !ok {
+			return nil
+		}
 
-	if strings.HasPrefix(content, "---") {
-		parts := bytes.Split([]byte(content), []byte("---"))
-		if len(parts) >= 3 {
-			remainingParts := parts[2:]
-			result := bytes.Join(remainingParts, []byte("---"))
-			return string(result)
+		if relPath == "." {
+			// The project folder is itself a bundle
+			relPath = filepath.Base(path)
 		}
-	}
 
-	return content
+		if info.IsDir() {
+			bundle, ok := extractor.(Bundle)
+			if !ok {
+				return nil
+			}
+
+			// Each document in a bundle is counted under its name in the bundle
+			items, err := bundle.Items(path)
+			if err != nil {
+				report(FileError{Path: relPath, Err: err})
+				return filepath.SkipDir
+			}
+			for _, item := range items {
+				name := relPath + "/" + item.Name
+				if err := visit(name, item.Path, item.Extractor); err != nil {
+					report(FileError{Path: name, Err: err})
+				}
+			}
+			return filepath.SkipDir
+		}
+
+		if err := visit(relPath, path, extractor); err != nil {
+			report(FileError{Path: relPath, Err: err})
+		}
+		return nil
+	})
 }
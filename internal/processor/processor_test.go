@@ -1,12 +1,60 @@
 package processor
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bwilson/verkounter/internal/counter"
+	"github.com/bwilson/verkounter/internal/ignore"
 )
 
-func TestStripFrontmatter(t *testing.T) {
+// writeProject creates a project folder holding files, keyed by their
+// slash-separated path in the folder, and returns the folder's path
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return dir
+}
+
+// streamMarkdown runs content through the stream a Markdown file read with
+// opts goes through, returning its counted and excluded text
+func streamMarkdown(content string, opts MarkdownOptions) (string, string) {
+	var prose, excluded strings.Builder
+	stream := &textStream{
+		doc:         newDocument(&prose, &excluded, nil),
+		markup:      &markdownExtractor{opts: opts},
+		excluded:    &markdownExtractor{opts: opts},
+		frontmatter: true,
+		raw:         opts.Raw,
+	}
+	readLines(strings.NewReader(content), stream.line)
+	stream.close()
+
+	return prose.String(), excluded.String()
+}
+
+func TestStreamFrontmatter(t *testing.T) {
 	tests := []struct {
 		name     string
 		content  string
@@ -52,61 +100,1598 @@ title: Test
 Some text --- more text`,
 			expected: "\n# Content\n\nSome text --- more text",
 		},
+		{
+			name:     "TOML frontmatter",
+			content:  "+++\ntitle = \"Test\"\n+++\n\n# Content",
+			expected: "\n# Content",
+		},
+		{
+			name:     "JSON frontmatter",
+			content:  "{\n  \"title\": \"Test {1}\"\n}\n\n# Content",
+			expected: "\n# Content",
+		},
+		{
+			name:     "Braces that are not JSON",
+			content:  "{ not json }\n\n# Content",
+			expected: "{ not json }\n\n# Content",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := stripFrontmatter(tt.content)
+			// Raw Markdown shows the body as it is
+			result, _ := streamMarkdown(tt.content, MarkdownOptions{Raw: true})
 			if result != tt.expected {
-				t.Errorf("stripFrontmatter() = %q, want %q", result, tt.expected)
+				t.Errorf("streamMarkdown() = %q, want %q", result, tt.expected)
 			}
 		})
 	}
 }
 
 func TestProcessMarkdownFiles(t *testing.T) {
-	tempDir := t.TempDir()
-
-	testFile1 := filepath.Join(tempDir, "test1.md")
-	content1 := `---
+	tempDir := writeProject(t, map[string]string{
+		"test1.md": `---
 title: Test1
 ---
 
 # Test Document 1
 
-This is test content.`
+This is test content.`,
+		"test2.md": `# Test Document 2
+
+No frontmatter here.`,
+		"test.txt": "Not a markdown file",
+	})
 
-	err := os.WriteFile(testFile1, []byte(content1), 0644)
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}})
 	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	if result.Words == 0 {
+		t.Error("Expected non-zero word count")
+	}
+
+	// "Test Document 1 This is test content." and "Test Document 2 No frontmatter here."
+	if result.Words != 13 {
+		t.Errorf("Words = %d, want 13", result.Words)
+	}
+
+	if result.Files["test1.md"] != 7 || result.Files["test2.md"] != 6 || len(result.Files) != 2 {
+		t.Errorf("Files = %v, want test1.md: 7, test2.md: 6", result.Files)
+	}
+}
+
+func TestStreamMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		opts     MarkdownOptions
+		expected string
+	}{
+		{
+			name:     "Headings and emphasis",
+			content:  "## Chapter *One* ##\n\nShe was **very** _tired_ of snake_case.",
+			expected: "Chapter One\n\nShe was very tired of snake_case.",
+		},
+		{
+			name:     "Links and images",
+			content:  "See [the docs](https://example.com \"Docs\") and ![a red door](door.png) or <https://example.com>.",
+			expected: "See the docs and  or .",
+		},
+		{
+			name:     "Alt text counted",
+			content:  "![a red door](door.png)",
+			opts:     MarkdownOptions{CountAltText: true},
+			expected: "a red door",
+		},
+		{
+			name:     "Fenced code excluded",
+			content:  "Before\n```go\nfunc main() {}\n```\nAfter",
+			expected: "Before\n\n\n\nAfter",
+		},
+		{
+			name:     "Fenced code counted",
+			content:  "Before\n~~~\ncode here\n~~~\nAfter",
+			opts:     MarkdownOptions{CountCodeBlocks: true},
+			expected: "Before\n\ncode here\n\nAfter",
+		},
+		{
+			name:     "Inline code",
+			content:  "Run `go test` now",
+			expected: "Run  now",
+		},
+		{
+			name:     "HTML comments",
+			content:  "Keep <!-- hidden --> this\n<!-- start\nmore hidden\nend -->done",
+			expected: "Keep  this\n\n\ndone",
+		},
+		{
+			name:     "Lists and blockquotes",
+			content:  "- [x] first item\n2. second item\n> quoted text",
+			expected: "first item\nsecond item\nquoted text",
+		},
+		{
+			name:     "Tables",
+			content:  "| Name | Role |\n|------|:----:|\n| Ada | Hero |",
+			expected: "Name   Role  \n\n  Ada   Hero",
+		},
+		{
+			name:     "Tables skipped",
+			content:  "Cast\n| Name | Role |\n|------|------|\n| Ada | Hero |\n\nEnd",
+			opts:     MarkdownOptions{SkipTables: true},
+			expected: "Cast\n\n\n\n\nEnd",
+		},
+		{
+			name:     "Rules and link definitions",
+			content:  "Text\n\n***\n\n[ref]: https://example.com\nMore",
+			expected: "Text\n\n\n\n\nMore",
+		},
+		{
+			name:     "Raw",
+			content:  "# Heading with [link](url)",
+			opts:     MarkdownOptions{Raw: true},
+			expected: "# Heading with [link](url)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := streamMarkdown(tt.content, tt.opts)
+			if result != tt.expected {
+				t.Errorf("streamMarkdown() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDocumentExclusionMarkers(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		expectedKept     string
+		expectedExcluded string
+	}{
+		{
+			name:             "No markers",
+			content:          "Plain text",
+			expectedKept:     "Plain text",
+			expectedExcluded: "",
+		},
+		{
+			name:             "Off and on markers",
+			content:          "Keep\n<!-- verkount:off -->\nCut scene\n<!--verkount:on-->\nKeep too",
+			expectedKept:     "Keep\n\n\n\nKeep too",
+			expectedExcluded: "Cut scene",
+		},
+		{
+			name:             "Inline percent comment",
+			content:          "Visible %%note to self%% text",
+			expectedKept:     "Visible  text",
+			expectedExcluded: "note to self",
+		},
+		{
+			name:             "Multi-line percent comment",
+			content:          "A\n%%\nOutline\n%%\nB",
+			expectedKept:     "A\n\n\n\nB",
+			expectedExcluded: "Outline",
+		},
+		{
+			name:             "Percent sign after a number",
+			content:          "Give it 100%% and %%not this%%",
+			expectedKept:     "Give it 100%% and",
+			expectedExcluded: "not this",
+		},
+		{
+			name:             "Unterminated off marker",
+			content:          "Start <!-- verkount:off --> rest\nof file",
+			expectedKept:     "Start",
+			expectedExcluded: "rest\nof file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kept, excluded strings.Builder
+			doc := newDocument(&kept, &excluded, nil)
+			for _, line := range strings.Split(tt.content, "\n") {
+				doc.Text(line)
+			}
+			if kept.String() != tt.expectedKept || excluded.String() != tt.expectedExcluded {
+				t.Errorf("Document = %q, %q, want %q, %q", kept.String(), excluded.String(), tt.expectedKept, tt.expectedExcluded)
+			}
+		})
+	}
+}
+
+func TestProcessMarkdownExclusionMarkersInCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		words    int
+		excluded int
+	}{
+		{
+			name:    "Mermaid comment in a code fence",
+			content: "Before the chart.\n```mermaid\n%% layout notes\ngraph TD\n```\nAfter the chart counts.",
+			words:   7,
+		},
+		{
+			name:    "Off marker in a code fence",
+			content: "Write\n~~~html\n<!-- verkount:off -->\n~~~\nto hide a scene.",
+			words:   5,
+		},
+		{
+			name:    "Percent signs in a code span",
+			content: "Type `%%` to start a comment.\nThis line counts too.",
+			words:   9,
+		},
+		{
+			name:    "Percent signs after a number",
+			content: "Give it 100%% every day.\nThe rest counts.",
+			words:   8,
+		},
+		{
+			name:     "Comment after a code fence",
+			content:  "```\n%%\n```\nKeep %%hidden note%% this.",
+			words:    2,
+			excluded: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := writeProject(t, map[string]string{"notes.md": tt.content})
+
+			result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}})
+			if err != nil {
+				t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+			}
+			if result.Words != tt.words || result.Excluded != tt.excluded {
+				t.Errorf("Words = %d, Excluded = %d, want %d and %d", result.Words, result.Excluded, tt.words, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestProcessMarkdownFilesCounts(t *testing.T) {
+	// The counted and excluded text each file should be reduced to
+	files := map[string]struct {
+		content  string
+		prose    string
+		excluded string
+	}{
+		"a.md": {
+			content:  "---\ntitle: A\n---\n\n# Chapter *One*\n\nShe said “hello” — twice.\n<!-- verkount:off -->\nCut scene.\n<!-- verkount:on -->\n",
+			prose:    "Chapter One\n\nShe said “hello” — twice.",
+			excluded: "Cut scene.",
+		},
+		"b.md": {
+			content:  "  \n\nNo frontmatter, [a link](https://example.com) and %%a note%% here.\r\nSecond line\r\n",
+			prose:    "No frontmatter, a link and  here.\nSecond line",
+			excluded: "a note",
+		},
+		"c.md": {
+			content: "---\nunclosed: frontmatter\n\nText after it.",
+			prose:   "unclosed: frontmatter\n\nText after it.",
+		},
+		"d.md": {
+			content: "吾輩は猫である。名前はまだ無い。\n\n```\ncode\n```\n",
+			prose:   "吾輩は猫である。名前はまだ無い。",
+		},
+		"e.md": {},
+		"g.md": {
+			content: "\n+++\ntitle = \"G\"\ntags = [\n  \"one\",\n]\n+++\nHugo *page*.\n",
+			prose:   "Hugo page.",
+		},
+		"h.md": {
+			content: "{\"title\": \"H\",\n \"draft\": true}\nZola page.\n",
+			prose:   "Zola page.",
+		},
+		"i.md": {
+			content: "{ braces } that are prose.\n",
+			prose:   "{ braces } that are prose.",
+		},
+		"sub/f.md": {
+			content: "| A | B |\n|---|---|\n| 1 | 2 |\n" + strings.Repeat("long ", 20000),
+			prose:   "A   B  \n\n  1   2  \n" + strings.TrimSpace(strings.Repeat("long ", 20000)),
+		},
+	}
+
+	contents := map[string]string{"ignored.txt": "Not counted"}
+	var names []string
+	for name, file := range files {
+		contents[name] = file.content
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tempDir := writeProject(t, contents)
+
+	// Files are counted one after another, separated by a space
+	var prose, excluded strings.Builder
+	for _, name := range names {
+		prose.WriteString(files[name].prose + " ")
+		excluded.WriteString(files[name].excluded + " ")
+	}
+
+	for _, counterName := range counter.Names() {
+		c, _ := counter.Lookup(counterName)
+
+		result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c})
+		if err != nil {
+			t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+		}
+
+		if want := c.Count(prose.String()); result.Words != want {
+			t.Errorf("%s: Words = %d, want %d", counterName, result.Words, want)
+		}
+		if want := c.Count(excluded.String()); result.Excluded != want {
+			t.Errorf("%s: Excluded = %d, want %d", counterName, result.Excluded, want)
+		}
+		for _, name := range names {
+			if want := c.Count(files[name].prose); result.Files[name] != want {
+				t.Errorf("%s: Files[%s] = %d, want %d", counterName, name, result.Files[name], want)
+			}
+		}
 	}
+}
+
+func TestProcessMarkdownFilesChapters(t *testing.T) {
+	manuscript := `---
+title: Novel
+---
+Epigraph words here.
+
+# Part One
+
+## Chapter 1
+
+One two three.
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+## Chapter 2
 
-	testFile2 := filepath.Join(tempDir, "test2.md")
-	content2 := `# Test Document 2
+Four five.
 
-No frontmatter here.`
+### Scene break
 
-	err = os.WriteFile(testFile2, []byte(content2), 0644)
+Six.
+`
+	tempDir := writeProject(t, map[string]string{
+		"manuscript.md": manuscript,
+		"notes.md":      "# Notes\n\nSeven eight.",
+		// Setext headings; the --- under a list item is a rule
+		"setext.md": "Opening *Words*\n===============\n\nOne two.\n\nSecond Part\n-----------\nThree four five.\n\n- item\n---\n\nSix.",
+	})
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}, ChapterLevel: 2})
 	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	expected := []Section{
+		{File: "manuscript.md", Words: 3},
+		{File: "manuscript.md", Heading: "Part One", Level: 1, Words: 2},
+		{File: "manuscript.md", Heading: "Chapter 1", Level: 2, Words: 5},
+		{File: "manuscript.md", Heading: "Chapter 2", Level: 2, Words: 7},
+		{File: "notes.md", Heading: "Notes", Level: 1, Words: 3},
+		{File: "setext.md", Heading: "Opening Words", Level: 1, Words: 4},
+		{File: "setext.md", Heading: "Second Part", Level: 2, Words: 7},
+	}
+
+	if len(result.Sections) != len(expected) {
+		t.Fatalf("Sections = %+v, want %+v", result.Sections, expected)
+	}
+	for i, section := range result.Sections {
+		if section != expected[i] {
+			t.Errorf("Sections[%d] = %+v, want %+v", i, section, expected[i])
+		}
+	}
+
+	if result.Sections[0].Label() != "manuscript.md" || result.Sections[1].Label() != "Part One" {
+		t.Errorf("unexpected labels %q, %q", result.Sections[0].Label(), result.Sections[1].Label())
+	}
+}
+
+// stripLines runs content through a markup stripper and joins the prose
+// words with single spaces
+func stripLines(m markupStripper, content string) string {
+	var prose []string
+	for _, line := range strings.Split(content, "\n") {
+		prose = append(prose, m.line(line))
+	}
+	if holder, ok := m.(lineHolder); ok {
+		prose = append(prose, holder.flush())
+	}
+	return strings.Join(strings.Fields(strings.Join(prose, "\n")), " ")
+}
+
+func TestMarkupStrippers(t *testing.T) {
+	tests := []struct {
+		name     string
+		markup   markupStripper
+		content  string
+		expected string
+	}{
+		{
+			name:     "Org headlines and drawers",
+			markup:   &orgExtractor{},
+			content:  "#+TITLE: Novel\n* TODO [#A] Chapter One :draft:\n:PROPERTIES:\n:ID: 42\n:END:\nSCHEDULED: <2024-01-01>\nShe *ran* to the /door/.",
+			expected: "Chapter One She ran to the door.",
+		},
+		{
+			name:     "Org blocks, links and lists",
+			markup:   &orgExtractor{},
+			content:  "# a comment\n#+BEGIN_SRC go\nfmt.Println()\n#+END_SRC\n#+begin_quote\nQuoted words\n#+end_quote\n- [ ] See [[https://example.com][the site]][fn:1]\n| a | b |\n|---+---|",
+			expected: "Quoted words See the site a b",
+		},
+		{
+			name:     "RST sections and directives",
+			markup:   &rstExtractor{},
+			content:  "=====\nTitle\n=====\n\n:Author: Me\n\n.. code-block:: python\n\n   print('x')\n\nBack to prose.\n\n.. note:: Keep this\n   :class: aside\n\n   and this.",
+			expected: "Title Back to prose. Keep this and this.",
+		},
+		{
+			name:     "RST inline markup and literal blocks",
+			markup:   &rstExtractor{},
+			content:  ".. _target:\n.. a comment\n   continues\n\nSee `the docs <https://example.com>`_ and :ref:`intro` with ``code`` [1]_.\n\nExample::\n\n   literal text\n\n* *Done*.",
+			expected: "See the docs and intro with code. Example: Done.",
+		},
+		{
+			name:     "AsciiDoc headings and attributes",
+			markup:   &asciidocExtractor{},
+			content:  "= Novel\n:author: Me\n:toc:\n\n== Chapter {num}\n\n[quote]\n____\nQuoted *words*.\n____\n\n.Listing\n----\ncode here\n----\n// comment",
+			expected: "Novel Chapter Quoted words.",
+		},
+		{
+			name:     "AsciiDoc inline macros and lists",
+			markup:   &asciidocExtractor{},
+			content:  "NOTE: Visit https://example.com[the site] or <<intro,the intro>>.\n* _One_ item footnote:[aside]\nterm:: definition\nimage::cover.png[Cover]\n|===\n| Cell one | Cell two\n|===",
+			expected: "Visit the site or the intro. One item aside term definition Cell one Cell two",
+		},
+		{
+			name:     "AsciiDoc block titles and prose starting with a dot",
+			markup:   &asciidocExtractor{},
+			content:  ".Ingredients\n* flour\n\n.Example\n[source,go]\n----\ncode\n----\n\n.NET is a framework\nfor apps.\n\n.Titled paragraph\n\n.NET at the end",
+			expected: "flour .NET is a framework for apps. .Titled paragraph .NET at the end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripLines(tt.markup, tt.content); got != tt.expected {
+				t.Errorf("prose = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarkupStripperHeadings(t *testing.T) {
+	org := &orgExtractor{}
+	org.line("** DONE Scene Two :pov:")
+	if level, title := org.heading(); level != 2 || title != "Scene Two" {
+		t.Errorf("org heading = %d %q, want 2 %q", level, title, "Scene Two")
+	}
+
+	markdown := &markdownExtractor{}
+	for _, tt := range []struct {
+		lines []string
+		level int
+		title string
+	}{
+		{[]string{"Part *One*", "========"}, 1, "Part One"},
+		{[]string{"Chapter Two", "---"}, 2, "Chapter Two"},
+		{[]string{"", "---"}, 0, ""},
+		{[]string{"- a list item", "---"}, 0, ""},
+		{[]string{"# Heading", "==="}, 0, ""},
+	} {
+		for _, line := range tt.lines {
+			markdown.line(line)
+		}
+		if level, title := markdown.heading(); level != tt.level || level > 0 && (title != tt.title || !markdown.underlined()) {
+			t.Errorf("markdown heading of %q = %d %q, want %d %q", tt.lines, level, title, tt.level, tt.title)
+		}
 	}
 
-	nonMdFile := filepath.Join(tempDir, "test.txt")
-	err = os.WriteFile(nonMdFile, []byte("Not a markdown file"), 0644)
+	rst := &rstExtractor{}
+	for _, tt := range []struct {
+		lines []string
+		level int
+		title string
+	}{
+		{[]string{"=========", " *Novel* ", "========="}, 1, "Novel"},
+		{[]string{"", "Chapter One", "==========="}, 2, "Chapter One"},
+		{[]string{"", "Scene", "-----"}, 3, "Scene"},
+		{[]string{"", "Chapter Two", "==========="}, 2, "Chapter Two"},
+		{[]string{"", "Too long a title", "---"}, 0, ""},
+		{[]string{"", "* a list item", "-------------"}, 0, ""},
+		{[]string{"", "----------"}, 0, ""},
+	} {
+		for _, line := range tt.lines {
+			rst.line(line)
+		}
+		if level, title := rst.heading(); level != tt.level || level > 0 && (title != tt.title || !rst.underlined()) {
+			t.Errorf("rst heading of %q = %d %q, want %d %q", tt.lines, level, title, tt.level, tt.title)
+		}
+	}
+
+	adoc := &asciidocExtractor{}
+	adoc.line("=== Part *Three*")
+	if level, title := adoc.heading(); level != 2 || title != "Part Three" {
+		t.Errorf("asciidoc heading = %d %q, want 2 %q", level, title, "Part Three")
+	}
+}
+
+func TestProcessMarkdownFilesExtensions(t *testing.T) {
+	files := map[string]string{
+		"one.md":    "# One\n\nMarkdown words.",
+		"two.org":   "* Two\nOrg words.",
+		"three.TXT": "Plain text words.",
+		"four.rst":  "Four\n====\n\nRST words.",
+		"five.adoc": "= Five\n\nAsciiDoc words.",
+		"six.xyz":   "not counted",
+	}
+	tempDir := writeProject(t, files)
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Words != 3 || len(result.Files) != 1 {
+		t.Errorf("default extensions counted %d words in %v, want 3 words in one.md", result.Words, result.Files)
+	}
+
+	result, err = ProcessMarkdownFiles(tempDir, Options{
+		Counter:      counter.UnicodeWords{},
+		Extensions:   []string{"md", ".org", "txt", "RST", "adoc"},
+		ChapterLevel: 1,
+	})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	expected := map[string]int{"one.md": 3, "two.org": 3, "three.TXT": 3, "four.rst": 3, "five.adoc": 3}
+	if result.Words != 15 || len(result.Files) != len(expected) {
+		t.Errorf("Words = %d, Files = %v, want 15 words in %v", result.Words, result.Files, expected)
+	}
+	for file, words := range expected {
+		if result.Files[file] != words {
+			t.Errorf("Files[%s] = %d, want %d", file, result.Files[file], words)
+		}
+	}
+
+	var headings []string
+	for _, section := range result.Sections {
+		headings = append(headings, section.Heading)
+	}
+	sort.Strings(headings)
+	if strings.Join(headings, ",") != ",Five,Four,One,Two" {
+		t.Errorf("section headings = %q", headings)
+	}
+
+	if _, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}, Extensions: []string{".xyz"}}); err == nil {
+		t.Error("Expected an error for an extension without an extractor")
+	}
+}
+
+// writeZip creates a zip archive at path holding the given files
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
 	if err != nil {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+}
+
+func TestProcessOfficeDocuments(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writeZip(t, filepath.Join(tempDir, "draft.docx"), map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Chapter One</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">The rain </w:t></w:r><w:del><w:r><w:delText>never</w:delText></w:r></w:del><w:ins><w:r><w:t>finally</w:t></w:r></w:ins><w:r><w:t xml:space="preserve"> stopped.</w:t></w:r><w:commentReference w:id="0"/></w:p>
+</w:body></w:document>`,
+		"word/comments.xml": `<w:comments xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:comment><w:p><w:r><w:t>Editor note</w:t></w:r></w:p></w:comment></w:comments>`,
+		"word/header1.xml":  `<w:hdr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p><w:r><w:t>Running head</w:t></w:r></w:p></w:hdr>`,
+	})
+
+	writeZip(t, filepath.Join(tempDir, "draft.odt"), map[string]string{
+		"content.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<office:body><office:text>
+<text:tracked-changes><text:changed-region><text:deletion><text:p>Deleted words</text:p></text:deletion></text:changed-region></text:tracked-changes>
+<text:h text:outline-level="2">Chapter Two</text:h>
+<text:p>She<text:s/>left<office:annotation><dc:creator>Ed</dc:creator><text:p>Too abrupt</text:p></office:annotation> early<text:note><text:note-citation>1</text:note-citation><text:note-body><text:p>A footnote.</text:p></text:note-body></text:note>.</text:p>
+</office:text></office:body></office:document-content>`,
+		"styles.xml": `<office:document-styles xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:master-styles><style:master-page><style:footer><text:p>Page footer</text:p></style:footer></style:master-page></office:master-styles></office:document-styles>`,
+	})
+
+	opts := Options{Counter: counter.UnicodeWords{}, Extensions: []string{"docx", "odt"}, ChapterLevel: 2}
+	result, err := ProcessMarkdownFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
 
-	result, err := ProcessMarkdownFiles(tempDir)
+	// "Chapter One The rain finally stopped." and "Chapter Two She left early A footnote."
+	if result.Files["draft.docx"] != 6 || result.Files["draft.odt"] != 7 {
+		t.Errorf("Files = %v, want draft.docx: 6, draft.odt: 7", result.Files)
+	}
+
+	expected := []Section{
+		{File: "draft.docx", Heading: "Chapter One", Level: 1, Words: 6},
+		{File: "draft.odt", Heading: "Chapter Two", Level: 2, Words: 7},
+	}
+	if len(result.Sections) != len(expected) || result.Sections[0] != expected[0] || result.Sections[1] != expected[1] {
+		t.Errorf("Sections = %+v, want %+v", result.Sections, expected)
+	}
+
+	opts.Office.HeadersFooters = true
+	result, err = ProcessMarkdownFiles(tempDir, opts)
 	if err != nil {
 		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
 	}
+	if result.Files["draft.docx"] != 8 || result.Files["draft.odt"] != 9 {
+		t.Errorf("Files with headers and footers = %v, want draft.docx: 8, draft.odt: 9", result.Files)
+	}
 
-	if result == "" {
-		t.Error("Expected non-empty result")
+	if err := os.WriteFile(filepath.Join(tempDir, "broken.docx"), []byte("not a zip"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	result, err = ProcessMarkdownFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
 	}
+	if _, ok := result.Files["broken.docx"]; ok {
+		t.Errorf("Expected unreadable broken.docx to be skipped, got %v", result.Files)
+	}
+}
 
-	if len(result) == 0 {
-		t.Error("Result should contain processed content")
+func TestExtractRTF(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Paragraphs and formatting",
+			content:  `{\rtf1\ansi{\fonttbl\f0\fswiss Helvetica;}{\colortbl;\red255\green255\blue255;}\f0\fs24 The \b bold\b0  word.\par Next\tab line.}`,
+			expected: "The bold word. | Next line.",
+		},
+		{
+			name:     "Escapes and unicode",
+			content:  `{\rtf1\ansi\uc1 Caf\'e9 \u8220\'93quoted\u8221\'94 \{braces\}\~ok}`,
+			expected: "Café “quoted” {braces} ok",
+		},
+		{
+			name:     "Ignored destinations and fields",
+			content:  `{\rtf1{\info{\title Draft}}{\*\comment hidden}{\header Page}Visible {\field{\*\fldinst HYPERLINK "x"}{\fldrslt link}} text.}`,
+			expected: "Visible link text.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prose strings.Builder
+			doc := newDocument(&prose, io.Discard, nil)
+			if err := extractRTF(strings.NewReader(tt.content), doc); err != nil {
+				t.Fatalf("extractRTF failed: %v", err)
+			}
+
+			var paragraphs []string
+			for _, line := range strings.Split(prose.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					paragraphs = append(paragraphs, line)
+				}
+			}
+			if got := strings.Join(paragraphs, " | "); got != tt.expected {
+				t.Errorf("extractRTF() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProcessScrivenerProject(t *testing.T) {
+	binder := `<?xml version="1.0" encoding="UTF-8"?>
+<ScrivenerProject Version="2.0">
+<Binder>
+<BinderItem UUID="D" Type="DraftFolder"><Title>Manuscript</Title><Children>
+  <BinderItem UUID="C1" Type="Folder"><Title>Chapter One</Title>
+    <MetaData><IncludeInCompile>Yes</IncludeInCompile></MetaData>
+    <Children>
+      <BinderItem UUID="S1" Type="Text"><Title>Arrival</Title><MetaData><IncludeInCompile>Yes</IncludeInCompile></MetaData></BinderItem>
+      <BinderItem UUID="S2" Type="Text"><Title>Cut Scene</Title><MetaData><IncludeInCompile>No</IncludeInCompile></MetaData></BinderItem>
+    </Children>
+  </BinderItem>
+  <BinderItem UUID="S3" Type="Text"><Title>Epilogue</Title><MetaData><IncludeInCompile>Yes</IncludeInCompile></MetaData></BinderItem>
+</Children></BinderItem>
+<BinderItem UUID="R" Type="ResearchFolder"><Title>Research</Title><Children>
+  <BinderItem UUID="N1" Type="Text"><Title>Notes</Title><MetaData><IncludeInCompile>Yes</IncludeInCompile></MetaData></BinderItem>
+</Children></BinderItem>
+</Binder>
+</ScrivenerProject>`
+
+	tempDir := writeProject(t, map[string]string{
+		"Novel.scriv/Novel.scrivx":                 binder,
+		"Novel.scriv/Files/Data/S1/content.rtf":    `{\rtf1\ansi The ship docked at dawn.\par}`,
+		"Novel.scriv/Files/Data/S2/content.rtf":    `{\rtf1\ansi Never used.}`,
+		"Novel.scriv/Files/Data/S3/content.rtf":    `{\rtf1\ansi The end.}`,
+		"Novel.scriv/Files/Data/N1/content.rtf":    `{\rtf1\ansi Research notes.}`,
+		"Novel.scriv/Files/Data/S1/synopsis.txt":   "Synopsis words",
+		"Novel.scriv/Files/Data/S3/notes.rtf":      `{\rtf1\ansi Scene notes.}`,
+		"Novel.scriv/Files/Data/C1/note.md":        "# Not counted",
+		"Novel.scriv/Files/Docs/unused/readme.txt": "Not counted",
+		"outline.md": "Outline words.",
+	})
+
+	expected := map[string]int{
+		"Novel.scriv/Chapter One/Arrival": 5,
+		"Novel.scriv/Epilogue":            2,
+		"outline.md":                      2,
+	}
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Words != 9 || len(result.Files) != len(expected) {
+		t.Errorf("Words = %d, Files = %v, want 9 words in %v", result.Words, result.Files, expected)
+	}
+	for file, words := range expected {
+		if result.Files[file] != words {
+			t.Errorf("Files[%s] = %d, want %d", file, result.Files[file], words)
+		}
+	}
+
+	// A bundle that is itself the project folder is counted under its own name
+	result, err = ProcessMarkdownFiles(filepath.Join(tempDir, "Novel.scriv"), Options{Counter: counter.UnicodeWords{}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Words != 7 || result.Files["Novel.scriv/Epilogue"] != 2 {
+		t.Errorf("Words = %d, Files = %v, want 7 words", result.Words, result.Files)
+	}
+}
+
+func TestProcessFountainScreenplay(t *testing.T) {
+	script := `Title: Big Fish
+Credit: written by
+Author: John August
+
+INT. KITCHEN - NIGHT #1#
+
+Rain hammers the window. /* cut this
+and this */
+
+MARY (V.O.)
+(quietly)
+Where were you?
+
+JOHN ^
+Out.
+
+CUT TO:
+
+EXT. STREET - DAY
+
+[[note to self]]
+John *walks* away.
+
+# Act Two
+
+= synopsis text
+
+.FLASHBACK
+
+> THE END <
+`
+	tempDir := writeProject(t, map[string]string{"script.fountain": script})
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Screenplay != nil || result.Words != 0 {
+		t.Errorf("Expected Fountain files to be ignored by default, got %d words, %+v", result.Words, result.Screenplay)
+	}
+
+	result, err = ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}, Extensions: []string{"fountain"}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	// Scene headings (7), cues and parenthetical (3), transition (2), action (9) and dialogue (4)
+	if result.Words != 25 {
+		t.Errorf("Words = %d, want 25", result.Words)
+	}
+
+	// 3 scene headings, 3 action blocks and a transition at 2 lines each, plus
+	// dialogue blocks of 4 and 3 lines
+	expected := Screenplay{Scenes: 3, Pages: 0.4, DialogueWords: 4, ActionWords: 9}
+	if result.Screenplay == nil || *result.Screenplay != expected {
+		t.Fatalf("Screenplay = %+v, want %+v", result.Screenplay, expected)
+	}
+	if ratio := result.Screenplay.DialogueRatio(); ratio < 0.30 || ratio > 0.31 {
+		t.Errorf("DialogueRatio() = %v, want 4/13", ratio)
+	}
+}
+
+func TestWrappedLines(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected int
+	}{
+		{"", 35, 1},
+		{"Short line.", 35, 1},
+		{"one two three four", 10, 2},
+		{"one two three four", 8, 3},
+		{"abcdefghijklmnopqrst", 8, 3},
+	}
+
+	for _, tt := range tests {
+		if got := wrappedLines(tt.text, tt.width); got != tt.expected {
+			t.Errorf("wrappedLines(%q, %d) = %d, want %d", tt.text, tt.width, got, tt.expected)
+		}
+	}
+}
+
+func TestLaTeXExtractor(t *testing.T) {
+	document := `\documentclass{article}
+\usepackage{amsmath}
+\title{Ignored Title}
+\begin{document}
+\section{Introduction}\label{sec:intro}
+We study \emph{sparse} graphs % a comment
+as in~\cite{knuth}, see \href{https://example.com}{the site}.
+Inline $x^2 + y$ math and \(a+b\) too, 50\% done.
+\begin{equation}
+  E = mc^2
+\end{equation}
+\[ \int f \]
+\begin{figure}[h]
+\includegraphics[width=\linewidth]{plot.png}
+\caption{Degree distribution.}
+\end{figure}
+\footnote{A long
+note.}
+\begin{thebibliography}{9}
+\bibitem{knuth} Donald Knuth.
+\end{thebibliography}
+\end{document}
+Trailing notes.`
+
+	tests := []struct {
+		name     string
+		opts     LaTeXOptions
+		expected string
+	}{
+		{
+			name:     "Defaults",
+			expected: "Introduction We study sparse graphs as in , see the site. Inline math and too, 50% done. Degree distribution. A long note. Donald Knuth.",
+		},
+		{
+			name:     "Bibliography and captions skipped",
+			opts:     LaTeXOptions{SkipBibliography: true, SkipCaptions: true},
+			expected: "Introduction We study sparse graphs as in , see the site. Inline math and too, 50% done. A long note.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripLines(&latexExtractor{opts: tt.opts}, document); got != tt.expected {
+				t.Errorf("prose = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	latex := &latexExtractor{}
+	latex.line(`\subsection*{Related \emph{Work}}`)
+	if level, title := latex.heading(); level != 3 || title != "Related Work" {
+		t.Errorf("heading = %d %q, want 3 %q", level, title, "Related Work")
+	}
+
+	latex.line(`\chapter[Short]{Long Title}`)
+	if level, title := latex.heading(); level != 1 || title != "Long Title" {
+		t.Errorf("heading = %d %q, want 1 %q", level, title, "Long Title")
+	}
+}
+
+func TestProcessLaTeXExclusionMarkers(t *testing.T) {
+	content := "Counted words here.\n%% a comment, not a marker\nStill counted.\n% verkount:off\nDraft paragraph.\n% verkount:on\nEnd."
+	tempDir := writeProject(t, map[string]string{"paper.tex": content})
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: counter.UnicodeWords{}, Extensions: []string{"tex"}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Words != 6 || result.Excluded != 2 {
+		t.Errorf("Words = %d, Excluded = %d, want 6 and 2", result.Words, result.Excluded)
+	}
+}
+
+func TestProcessNotebooksAndLiterateDocuments(t *testing.T) {
+	notebook := `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Analysis\n", "\n", "We load the **data**.\n", "` + "```" + `\n", "not counted\n", "` + "```" + `"]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "outputs": [{"output_type": "stream", "text": ["lots of output words\n"]}], "source": ["import pandas as pd\n", "# comment words"]},
+  {"cell_type": "raw", "metadata": {}, "source": "raw cell words"},
+  {"cell_type": "markdown", "metadata": {}, "source": "Then we plot it."}
+ ],
+ "metadata": {"kernelspec": {"name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+	rmd := `---
+title: "Report"
+output: html_document
+---
+
+# Results
+
+` + "```{r setup, include=FALSE}" + `
+library(ggplot2)
+` + "```" + `
+
+The mean is ` + "`r mean(x)`" + ` overall.
+
+::: {.callout-note}
+A callout sentence.
+:::
+`
+
+	tempDir := writeProject(t, map[string]string{"analysis.ipynb": notebook, "report.Rmd": rmd, "essay.qmd": rmd})
+
+	opts := Options{Counter: counter.UnicodeWords{}, Extensions: []string{"ipynb", "rmd", "qmd"}, ChapterLevel: 1}
+	result, err := ProcessMarkdownFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	// "Analysis We load the data. Then we plot it." and "Results The mean is overall. A callout sentence."
+	expected := map[string]int{"analysis.ipynb": 9, "report.Rmd": 8, "essay.qmd": 8}
+	for file, words := range expected {
+		if result.Files[file] != words {
+			t.Errorf("Files[%s] = %d, want %d", file, result.Files[file], words)
+		}
+	}
+
+	var headings []string
+	for _, section := range result.Sections {
+		headings = append(headings, section.Heading)
+	}
+	sort.Strings(headings)
+	if strings.Join(headings, ",") != "Analysis,Results,Results" {
+		t.Errorf("section headings = %q", headings)
+	}
+
+	// Executable chunks stay out even when fenced code blocks are counted
+	opts.Markdown.CountCodeBlocks = true
+	result, err = ProcessMarkdownFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Files["report.Rmd"] != 8 || result.Files["analysis.ipynb"] != 11 {
+		t.Errorf("Files with code blocks counted = %v", result.Files)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "broken.ipynb"), []byte(`[1, 2]`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	var prose strings.Builder
+	if err := (notebookFormat{}).Extract(filepath.Join(tempDir, "broken.ipynb"), opts, newDocument(&prose, io.Discard, nil)); err == nil {
+		t.Error("Expected an error for a file that is not a notebook")
+	}
+}
+
+func TestProcessHTMLAndEPUB(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head><title>Page title</title><style>p { color: red; }</style></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<h1>Chapter One</h1>
+<p>It was a <em>dark</em> and stormy&nbsp;night.<br>
+The end &amp; more.</p>
+<!-- a comment -->
+<script>var words = "not counted";</script>
+</body>
+</html>`
+	tempDir := writeProject(t, map[string]string{"draft.html": page})
+
+	chapter := func(heading, text string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>` + heading + `</title></head>
+<body><section epub:type="chapter"><h2>` + heading + `</h2><p>` + text + `</p></section></body></html>`
+	}
+
+	writeZip(t, filepath.Join(tempDir, "book.epub"), map[string]string{
+		"mimetype": "application/epub+zip",
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+<manifest>
+  <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+  <item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+  <item id="c2" href="text/chapter2.xhtml" media-type="application/xhtml+xml"/>
+  <item id="css" href="style.css" media-type="text/css"/>
+</manifest>
+<spine><itemref idref="nav"/><itemref idref="c2"/><itemref idref="c1"/></spine>
+</package>`,
+		"OEBPS/nav.xhtml":            chapter("Contents", "Chapter one, chapter two"),
+		"OEBPS/text/chapter 1.xhtml": chapter("First", "Words in the first chapter."),
+		"OEBPS/text/chapter2.xhtml":  chapter("Second", "Two more."),
+		"OEBPS/style.css":            "body { margin: 0 }",
+	})
+
+	result, err := ProcessMarkdownFiles(tempDir, Options{
+		Counter:      counter.UnicodeWords{},
+		Extensions:   []string{"html", "epub"},
+		ChapterLevel: 2,
+	})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	// "Chapter One It was a dark and stormy night. The end more." and
+	// "Second Two more. First Words in the first chapter."
+	if result.Files["draft.html"] != 12 || result.Files["book.epub"] != 9 {
+		t.Errorf("Files = %v, want draft.html: 12, book.epub: 9", result.Files)
+	}
+
+	expected := []Section{
+		{File: "book.epub", Heading: "Second", Level: 2, Words: 3},
+		{File: "book.epub", Heading: "First", Level: 2, Words: 6},
+		{File: "draft.html", Heading: "Chapter One", Level: 1, Words: 12},
+	}
+	if len(result.Sections) != len(expected) {
+		t.Fatalf("Sections = %+v, want %+v", result.Sections, expected)
+	}
+	for i, section := range result.Sections {
+		if section != expected[i] {
+			t.Errorf("Sections[%d] = %+v, want %+v", i, section, expected[i])
+		}
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  string
+		words int
+	}{
+		{
+			name:  "inline script with comparisons",
+			page:  "<p>Before the script.</p><script>if (a < b && c) { document.write('<p>no</p>'); }</script><p>After it.</p>",
+			words: 5,
+		},
+		{
+			name:  "bare less-than in text",
+			page:  "<p>5 < 6 is true</p><p>and so is 1 <2.</p>",
+			words: 9,
+		},
+		{
+			name:  "style with child selectors",
+			page:  "<style>ul > li { margin: 0 }</style><p>Only this.</p>",
+			words: 2,
+		},
+		{
+			name:  "optional end tags and void elements in navigation",
+			page:  "<nav><ul><li>Home<li>About <img src=x.png><br></ul></nav><p>The story starts.</p>",
+			words: 3,
+		},
+		{
+			name:  "EPUB table of contents",
+			page:  `<section epub:type="toc"><ol><li>Chapter one</li></ol></section><p>Prose here.</p>`,
+			words: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prose strings.Builder
+			if err := extractHTML(strings.NewReader(tt.page), newDocument(&prose, io.Discard, nil)); err != nil {
+				t.Fatalf("extractHTML failed: %v", err)
+			}
+			if got := (counter.UnicodeWords{}).Count(prose.String()); got != tt.words {
+				t.Errorf("words = %d, want %d (text %q)", got, tt.words, prose.String())
+			}
+		})
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		body   string
+		want   map[string]any
+	}{
+		{
+			name:   "YAML",
+			format: yamlFrontmatter,
+			body:   "title: Test\ntags: [a, b]\nverkount: false",
+			want:   map[string]any{"title": "Test", "tags": []any{"a", "b"}, "verkount": false},
+		},
+		{
+			name:   "JSON",
+			format: jsonFrontmatter,
+			body:   `{"title": "Test", "weight": 2, "extra": {"pov": "Ann"}}`,
+			want:   map[string]any{"title": "Test", "weight": 2.0, "extra": map[string]any{"pov": "Ann"}},
+		},
+		{
+			name:   "TOML values",
+			format: tomlFrontmatter,
+			body: `title = "A \"quoted\" title" # comment
+'literal key' = 'C:\path'
+weight = 1_000
+ratio = 0.5
+draft = false
+date = 2024-01-02T10:00:00Z
+tags = [
+  "one", # first
+  "two",
+]
+author = { name = "Ann", pov = true }
+description = """
+Two "quoted"
+lines with \"escapes\""""`,
+			want: map[string]any{
+				"title":       `A "quoted" title`,
+				"literal key": `C:\path`,
+				"weight":      int64(1000),
+				"ratio":       0.5,
+				"draft":       false,
+				"date":        time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+				"tags":        []any{"one", "two"},
+				"author":      map[string]any{"name": "Ann", "pov": true},
+				"description": "Two \"quoted\"\nlines with \"escapes\"",
+			},
+		},
+		{
+			name:   "TOML tables",
+			format: tomlFrontmatter,
+			body: `site.name = "Blog"
+[extra]
+status = "draft"
+[extra.pov]
+name = "Ann"
+[[characters]]
+name = "Ann"
+[[characters]]
+name = "Bo"`,
+			want: map[string]any{
+				"site":       map[string]any{"name": "Blog"},
+				"extra":      map[string]any{"status": "draft", "pov": map[string]any{"name": "Ann"}},
+				"characters": []map[string]any{{"name": "Ann"}, {"name": "Bo"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFrontmatter(tt.format, tt.body)
+			if err != nil {
+				t.Fatalf("parseFrontmatter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFrontmatter() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	for _, body := range []string{"title = ", "title = \"open", "[table\nname", "just words"} {
+		if _, err := parseFrontmatter(tomlFrontmatter, body); err == nil {
+			t.Errorf("parseFrontmatter(%q) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestProcessMarkdownFilesMetadata(t *testing.T) {
+	files := map[string]string{
+		"yaml.md":  "---\ntitle: One\n---\nFirst file.",
+		"toml.md":  "+++\ntitle = \"Two\"\n+++\nSecond file.",
+		"json.md":  "{\n  \"title\": \"Three\"\n}\nThird file.",
+		"plain.md": "No metadata here.",
+		"bad.md":   "---\ntitle: [unclosed\n---\nFifth file.",
+	}
+	tempDir := writeProject(t, files)
+
+	c, _ := counter.Lookup("heuristic")
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	if result.Words != 11 {
+		t.Errorf("Words = %d, want 11", result.Words)
+	}
+
+	want := map[string]map[string]any{
+		"yaml.md": {"title": "One"},
+		"toml.md": {"title": "Two"},
+		"json.md": {"title": "Three"},
+	}
+	if !reflect.DeepEqual(result.Metadata, want) {
+		t.Errorf("Metadata = %v, want %v", result.Metadata, want)
+	}
+}
+
+func TestProcessMarkdownFilesBreakdown(t *testing.T) {
+	files := map[string]string{
+		"one.md":   "---\nstatus: draft\npov: Ann\ntags: [battle, night]\n---\n# One\n\nOne two three.",
+		"two.md":   "+++\nstatus = \"final\"\n[extra]\npov = \"Bo\"\ntags = \"night, city\"\n+++\nFour five.",
+		"three.md": "Six seven eight nine.",
+		"notes.md": "---\nverkount: false\nstatus: draft\n---\n# Notes\n\nNot counted at all.",
+		"cut.md":   "{\"verkount\": \"off\"}\nNeither is this.",
+	}
+	tempDir := writeProject(t, files)
+
+	c, _ := counter.Lookup("unicode-words")
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c, ChapterLevel: 1})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	if result.Words != 10 {
+		t.Errorf("Words = %d, want 10", result.Words)
+	}
+	if _, ok := result.Files["notes.md"]; ok {
+		t.Errorf("Files includes notes.md, which sets verkount: false")
+	}
+	if _, ok := result.Files["cut.md"]; ok {
+		t.Errorf("Files includes cut.md, which sets verkount: off")
+	}
+	for _, section := range result.Sections {
+		if section.File == "notes.md" {
+			t.Errorf("Sections include %v from an excluded file", section)
+		}
+	}
+
+	want := &Breakdown{
+		Status: map[string]int{"draft": 4, "final": 2},
+		POV:    map[string]int{"Ann": 4, "Bo": 2},
+		Tags:   map[string]int{"battle": 4, "night": 6, "city": 2},
+	}
+	if !reflect.DeepEqual(result.Breakdown, want) {
+		t.Errorf("Breakdown = %+v, want %+v", result.Breakdown, want)
+	}
+}
+
+func TestExcludedByMetadata(t *testing.T) {
+	tests := []struct {
+		metadata map[string]any
+		want     bool
+	}{
+		{nil, false},
+		{map[string]any{"title": "Kept"}, false},
+		{map[string]any{"verkount": true}, false},
+		{map[string]any{"verkount": false}, true},
+		{map[string]any{"verkount": "No"}, true},
+		{map[string]any{"params": map[string]any{"verkount": false}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := excludedByMetadata(tt.metadata); got != tt.want {
+			t.Errorf("excludedByMetadata(%v) = %v, want %v", tt.metadata, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMarkdownFilesReportsErrors(t *testing.T) {
+	files := map[string]string{
+		"good.md":     "Counted words here.",
+		"broken.docx": "not a zip archive",
+	}
+	tempDir := writeProject(t, files)
+	// A Scrivener project without its binder
+	if err := os.MkdirAll(filepath.Join(tempDir, "Empty.scriv"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	c, _ := counter.Lookup("unicode-words")
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c, Extensions: []string{"md", "docx", "scriv"}})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	if result.Words != 3 {
+		t.Errorf("Words = %d, want 3", result.Words)
+	}
+
+	var paths []string
+	for _, fileErr := range result.Errors {
+		paths = append(paths, fileErr.Path)
+		if fileErr.Err == nil || !strings.HasPrefix(fileErr.Error(), fileErr.Path+": ") {
+			t.Errorf("Error() = %q, want it to name %s and its cause", fileErr.Error(), fileErr.Path)
+		}
+	}
+	sort.Strings(paths)
+	if want := []string{"Empty.scriv", "broken.docx"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Errors for %v, want %v", paths, want)
+	}
+	if _, ok := result.Files["broken.docx"]; ok {
+		t.Errorf("Files includes broken.docx, which could not be read")
+	}
+}
+
+// encodeUTF16 encodes s as UTF-16, little-endian unless bigEndian is set
+func encodeUTF16(s string, bigEndian bool) []byte {
+	var b []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			b = append(b, byte(unit>>8), byte(unit))
+		} else {
+			b = append(b, byte(unit), byte(unit>>8))
+		}
+	}
+	return b
+}
+
+func TestOpenTextEncodings(t *testing.T) {
+	text := "---\ntitle: Été\n---\nCafé “quoted” 😀 text."
+	want := "Café “quoted” 😀 text."
+
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding FileEncoding
+	}{
+		{"UTF-8", []byte(text), FileEncoding{}},
+		{"UTF-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...), FileEncoding{}},
+		{"UTF-16LE with BOM", append([]byte{0xFF, 0xFE}, encodeUTF16(text, false)...), FileEncoding{Name: "utf-16le"}},
+		{"UTF-16BE with BOM", append([]byte{0xFE, 0xFF}, encodeUTF16(text, true)...), FileEncoding{Name: "utf-16be"}},
+		{"UTF-16LE without BOM", encodeUTF16(text, false), FileEncoding{Name: "utf-16le", Guessed: true}},
+		{
+			name:     "Windows-1252",
+			content:  []byte("---\ntitle: \xC9t\xE9\n---\nCaf\xE9 \x93quoted\x94 \xF0\x9F\x98\x80 text."),
+			encoding: FileEncoding{Name: "windows-1252", Guessed: true},
+		},
+	}
+
+	tempDir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.name+".md")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			var prose strings.Builder
+			doc := newDocument(&prose, io.Discard, nil)
+			if err := (markdownFormat{}).Extract(path, Options{}, doc); err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+
+			if prose.String() != want {
+				t.Errorf("prose = %q, want %q", prose.String(), want)
+			}
+			if doc.encoding != tt.encoding {
+				t.Errorf("encoding = %+v, want %+v", doc.encoding, tt.encoding)
+			}
+			if title := doc.Metadata()["title"]; title != "Été" {
+				t.Errorf("title = %q, want %q", title, "Été")
+			}
+		})
+	}
+}
+
+func TestDecodingReaderSplitsCharacters(t *testing.T) {
+	// Characters are split across reads of a single byte
+	text := strings.Repeat("ä😀x", 100)
+
+	for _, tt := range []struct {
+		name   string
+		input  []byte
+		decode func(src []byte, eof bool, dst []byte) ([]byte, int)
+	}{
+		{"UTF-8", []byte(text), (&textFile{}).decodeUTF8},
+		{"UTF-16", encodeUTF16(text, false), utf16Decoder(binary.LittleEndian)},
+	} {
+		reader := &decodingReader{r: iotest.OneByteReader(bytes.NewReader(tt.input)), decode: tt.decode}
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("%s: ReadAll failed: %v", tt.name, err)
+		}
+		if string(got) != text {
+			t.Errorf("%s: decoded %q, want %q", tt.name, got, text)
+		}
+	}
+}
+
+func TestParseFileSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  FileSize
+		text  string
+	}{
+		{"0", 0, "0B"},
+		{"512", 512, "512B"},
+		{"500KB", 500 * KB, "500KB"},
+		{"20 mb", 20 * MB, "20MB"},
+		{"1.5GB", 1536 * MB, "1536MB"},
+		{"2048MB", 2 * GB, "2GB"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFileSize(tt.input)
+		if err != nil {
+			t.Errorf("ParseFileSize(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFileSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+		if got.String() != tt.text {
+			t.Errorf("FileSize(%d).String() = %q, want %q", got, got.String(), tt.text)
+		}
+	}
+
+	for _, input := range []string{"", "MB", "-1KB", "ten MB"} {
+		if _, err := ParseFileSize(input); err == nil {
+			t.Errorf("ParseFileSize(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want bool
+	}{
+		{"Prose", []byte("Plain prose.\r\n\tIndented\f"), false},
+		{"Windows-1252", []byte("Caf\xE9 cr\xE8me br\xFBl\xE9e, \x93quoted\x94."), false},
+		{"Cut off character", []byte("Ends in the middle of \xE2\x80"), false},
+		{"NUL byte", []byte("%PDF-1.4\n\x00stream"), true},
+		{"Compressed stream", []byte("x\x9c\xed\xbd\x07\x9c\x1c\xc5\xb5\xf7\x8f\x01\x02\x83\xc4\x94\xfe\xd1"), true},
+	}
+
+	for _, tt := range tests {
+		if got := looksBinary(tt.head); got != tt.want {
+			t.Errorf("%s: looksBinary() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMarkdownFilesSkipsBinaryAndOversizedFiles(t *testing.T) {
+	tempDir := writeProject(t, map[string]string{
+		"chapter.md":    "Four words of prose.",
+		"export.md":     "%PDF-1.7\n%\xE2\xE3\xCF\xD3\n1 0 obj\n<< /Length 5 >>\nstream\n\x00\x01\x02\nendstream",
+		"huge.md":       strings.Repeat("word ", 1000),
+		"utf16-note.md": "\xFF\xFE" + string(encodeUTF16("Not binary", false)),
+	})
+
+	c, _ := counter.Lookup("unicode-words")
+	result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c, MaxFileSize: 1 * KB})
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+
+	if result.Words != 6 {
+		t.Errorf("Words = %d, want 6", result.Words)
+	}
+	want := []SkippedFile{
+		{Path: "export.md", Reason: "binary content"},
+		{Path: "huge.md", Reason: "larger than 1KB"},
+	}
+	if !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", result.Skipped, want)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", result.Errors)
+	}
+}
+
+func TestProcessMarkdownFilesIgnoreRules(t *testing.T) {
+	files := map[string]string{
+		ignore.FileName:          "research/\n.obsidian/\n",
+		"chapters/01.md":         "One two.",
+		"chapters/02.md":         "Three four five.",
+		"chapters/outline.md":    "Left out by an exclude glob.",
+		"research/sources.md":    "Not counted.",
+		".obsidian/workspace.md": "Not counted either.",
+		"notes.md":               "Left out by the include globs.",
+	}
+	tempDir := writeProject(t, files)
+
+	c, _ := counter.Lookup("unicode-words")
+	// An include glob naming a folder matches the files inside it
+	opts := Options{Counter: c, Include: []string{"chapters/"}, Exclude: []string{"outline.md"}}
+	result, err := ProcessMarkdownFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+	}
+	if result.Words != 5 {
+		t.Errorf("Words = %d, want 5", result.Words)
+	}
+
+	names, fileErrors, err := ListFiles(tempDir, opts)
+	if err != nil {
+		t.Fatalf("ListFiles failed: %v", err)
+	}
+	if want := []string{"chapters/01.md", "chapters/02.md"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListFiles() = %v, want %v", names, want)
+	}
+	if len(fileErrors) != 0 {
+		t.Errorf("ListFiles() errors = %v, want none", fileErrors)
+	}
+}
+
+func TestProcessMarkdownFilesCategories(t *testing.T) {
+	files := map[string]string{
+		"chapters/01.md":       "One two three.",
+		"chapters/02.md":       "Four five.",
+		"notes/ideas.md":       "Six seven eight nine.",
+		"notes/research/a.md":  "Ten.",
+		"research/sources.md":  "Eleven twelve.",
+		"research/summary.txt": "Not counted.",
+	}
+	tempDir := writeProject(t, files)
+
+	c, _ := counter.Lookup("unicode-words")
+	tests := []struct {
+		name       string
+		categories Categories
+		want       map[string]int
+	}{
+		{
+			name: "no categories",
+			want: map[string]int{DefaultCategory: 12},
+		},
+		{
+			name: "first matching category wins",
+			categories: Categories{
+				{Name: "notes", Globs: []string{"notes/"}},
+				{Name: "research", Globs: []string{"research/"}},
+			},
+			want: map[string]int{DefaultCategory: 5, "notes": 5, "research": 2},
+		},
+		{
+			name: "unanchored globs",
+			categories: Categories{
+				{Name: "research", Globs: []string{"research/"}},
+				{Name: "notes", Globs: []string{"/notes/**"}},
+			},
+			want: map[string]int{DefaultCategory: 5, "notes": 4, "research": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ProcessMarkdownFiles(tempDir, Options{Counter: c, Extensions: []string{"md"}, Categories: tt.categories})
+			if err != nil {
+				t.Fatalf("ProcessMarkdownFiles failed: %v", err)
+			}
+			if !reflect.DeepEqual(result.Categories, tt.want) {
+				t.Errorf("Categories = %v, want %v", result.Categories, tt.want)
+			}
+			if result.Words != 12 {
+				t.Errorf("Words = %d, want 12", result.Words)
+			}
+		})
 	}
 }
@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	rstDirectivePattern    = regexp.MustCompile(`^(\s*)\.\.\s+([\w:-]+)::(.*)$`)
+	rstSubstitutionPattern = regexp.MustCompile(`^\s*\.\.\s+\|[^|]+\|\s+[\w:-]+::`)
+	rstTargetPattern       = regexp.MustCompile(`^\s*\.\.\s+_`)
+	rstFootnotePattern     = regexp.MustCompile(`^\s*\.\.\s+\[[^\]]+\]\s*`)
+	rstCommentPattern      = regexp.MustCompile(`^(\s*)\.\.(\s|$)`)
+	rstFieldPattern        = regexp.MustCompile(`^\s*:[\w -]+:(\s|$)`)
+	rstListPattern         = regexp.MustCompile(`^\s*(?:[-*+•]|\(?(?:\d+|#|[a-zA-Z]|[ivxlcdmIVXLCDM]+)[.)])\s+`)
+	rstLineBlockPattern    = regexp.MustCompile(`^\s*\|\s`)
+	rstTableBorder         = regexp.MustCompile(`^\s*[+|][-=+|:\s]*[+|]\s*$|^\s*=+(\s+=+)*\s*$`)
+	rstRolePattern         = regexp.MustCompile(`:[\w:-]+:` + "`" + `([^` + "`" + `]*)` + "`")
+	rstLinkPattern         = regexp.MustCompile("`" + `([^` + "`" + `<]*?)\s*<[^>]*>` + "`" + `__?`)
+	rstReferencePattern    = regexp.MustCompile("`" + `([^` + "`" + `]+)` + "`" + `__?`)
+	rstLiteralPattern      = regexp.MustCompile("``([^`]*)``")
+	rstInterpretedPattern  = regexp.MustCompile("`" + `([^` + "`" + `]+)` + "`")
+	rstFootnoteRefPattern  = regexp.MustCompile(`\s*\[(?:\d+|#[\w-]*|\*|[\w-]+)\]_`)
+	rstNamedRefPattern     = regexp.MustCompile(`\b([\w-]+)__?\b`)
+	rstSubstitutionRef     = regexp.MustCompile(`\|([^|\s][^|]*)\|_?`)
+	rstEmphasisPattern     = regexp.MustCompile(`(^|[\s(\-"'])\*{1,2}([^*\s][^*]*?)\*{1,2}($|[\s)\-.,;:!?'"])`)
+)
+
+// rstProseDirectives are the directives whose body is counted as prose
+var rstProseDirectives = map[string]bool{
+	"note": true, "tip": true, "hint": true, "important": true, "warning": true,
+	"caution": true, "danger": true, "error": true, "attention": true, "admonition": true,
+	"topic": true, "sidebar": true, "epigraph": true, "highlights": true, "pull-quote": true,
+	"compound": true, "container": true, "rubric": true,
+}
+
+// rstExtractor strips reStructuredText markup: directives such as code and
+// image blocks, comments, targets, section adornments, field lists, literal
+// blocks, roles and inline markup. A section title is reported as a heading
+// at its underline, and its level follows the order in which each adornment
+// style first appears in the file.
+type rstExtractor struct {
+	skipIndent  int  // Indentation of the block being skipped, or -1 when not skipping
+	skipping    bool // Inside a block whose indented body is not counted
+	inOptions   bool // Reading the :option: lines of a prose directive
+	optionsFrom int  // Indentation of the prose directive
+	literal     bool // The previous paragraph ended in ::, so an indented literal block may follow
+
+	level    int      // Level of the section whose underline was the last line, 0 if it was not one
+	title    string   // Prose of the last section title
+	text     string   // Prose of the last line when it was text that an underline can make a title
+	width    int      // Length of that line, which its underline must match
+	overline rune     // Adornment character of the last line when it was no underline, maybe the overline of a title
+	over     rune     // Adornment character of the line before the last
+	styles   []string // Adornment styles in the order they first appeared, one per level
+}
+
+func (r *rstExtractor) heading() (int, string) {
+	return r.level, r.title
+}
+
+func (r *rstExtractor) underlined() bool {
+	return r.level > 0
+}
+
+func (r *rstExtractor) line(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	text, width, over := r.text, r.width, r.over
+	r.over, r.overline = r.overline, 0
+	r.level, r.text = 0, ""
+
+	if r.skipping {
+		if trimmed == "" || indent > r.skipIndent {
+			return ""
+		}
+		r.skipping = false
+	}
+
+	if r.inOptions {
+		if trimmed != "" && indent > r.optionsFrom && rstFieldPattern.MatchString(line) {
+			return ""
+		}
+		r.inOptions = false
+	}
+
+	if r.literal {
+		if trimmed == "" {
+			return ""
+		}
+		r.literal = false
+		if indent > 0 {
+			r.skipIndent = indent - 1
+			r.skipping = true
+			return ""
+		}
+	}
+
+	if m := rstDirectivePattern.FindStringSubmatch(line); m != nil {
+		name := strings.ToLower(m[2])
+		if rstProseDirectives[name] {
+			r.inOptions = true
+			r.optionsFrom = len(m[1])
+			return stripRSTInline(m[3])
+		}
+		r.skip(len(m[1]))
+		return ""
+	}
+
+	if rstSubstitutionPattern.MatchString(line) || rstTargetPattern.MatchString(line) ||
+		rstFootnotePattern.MatchString(line) {
+		r.skip(indent)
+		return ""
+	}
+
+	if m := rstCommentPattern.FindStringSubmatch(line); m != nil {
+		r.skip(len(m[1]))
+		return ""
+	}
+
+	if isAdornment(trimmed) {
+		char, _ := utf8.DecodeRuneInString(trimmed)
+		if text != "" && indent == 0 && utf8.RuneCountInString(trimmed) >= width {
+			// An underline at least as long as the text above makes it a section title
+			r.level, r.title = r.sectionLevel(char, over == char), text
+		} else {
+			r.overline = char
+		}
+		return ""
+	}
+
+	if rstTableBorder.MatchString(line) {
+		return ""
+	}
+
+	if rstFieldPattern.MatchString(line) {
+		// Docinfo and other field lists hold metadata rather than prose
+		return ""
+	}
+
+	// A line of plain text may turn out to be a section title
+	title := !rstListPattern.MatchString(line) && !rstLineBlockPattern.MatchString(line)
+
+	if strings.HasSuffix(trimmed, "::") {
+		// "Paragraph::" reads as "Paragraph:", and a lone "::" disappears
+		title = false
+		r.literal = true
+		if trimmed == "::" {
+			return ""
+		}
+		line = strings.TrimSuffix(strings.TrimRight(line, " \t"), ":")
+	}
+
+	if strings.HasPrefix(trimmed, "|") && strings.HasSuffix(trimmed, "|") && len(trimmed) > 1 {
+		// Row of a grid table
+		line = strings.ReplaceAll(line, "|", " ")
+		title = false
+	}
+	line = rstLineBlockPattern.ReplaceAllString(line, "")
+	line = rstListPattern.ReplaceAllString(line, "")
+
+	prose := stripRSTInline(line)
+	if title && strings.TrimSpace(prose) != "" {
+		r.text, r.width = strings.TrimSpace(prose), utf8.RuneCountInString(trimmed)
+	}
+	return prose
+}
+
+// sectionLevel returns the level of sections adorned with char, under only
+// or over and under the title, numbering new styles as they appear
+func (r *rstExtractor) sectionLevel(char rune, overlined bool) int {
+	style := string(char)
+	if overlined {
+		style += style
+	}
+	for i, seen := range r.styles {
+		if seen == style {
+			return i + 1
+		}
+	}
+	r.styles = append(r.styles, style)
+	return len(r.styles)
+}
+
+// skip ignores the indented body that follows a line at the given indentation
+func (r *rstExtractor) skip(indent int) {
+	r.skipIndent = indent
+	r.skipping = true
+}
+
+// isAdornment reports whether line is a section title over- or underline:
+// a single punctuation character repeated
+func isAdornment(line string) bool {
+	if len(line) < 2 {
+		return false
+	}
+	first := rune(line[0])
+	if !unicode.IsPunct(first) && !unicode.IsSymbol(first) {
+		return false
+	}
+	for _, r := range line {
+		if r != first {
+			return false
+		}
+	}
+	return true
+}
+
+// stripRSTInline removes roles, hyperlink references, footnote references
+// and inline markup from a line of reStructuredText
+func stripRSTInline(line string) string {
+	line = rstLiteralPattern.ReplaceAllString(line, "$1")
+	line = rstRolePattern.ReplaceAllString(line, "$1")
+	line = rstLinkPattern.ReplaceAllString(line, "$1")
+	line = rstReferencePattern.ReplaceAllString(line, "$1")
+	line = rstInterpretedPattern.ReplaceAllString(line, "$1")
+	line = rstFootnoteRefPattern.ReplaceAllString(line, "")
+	line = rstNamedRefPattern.ReplaceAllString(line, "$1")
+	line = rstSubstitutionRef.ReplaceAllString(line, "$1")
+	line = rstEmphasisPattern.ReplaceAllString(line, "$1$2$3")
+	return line
+}
@@ -0,0 +1,243 @@
+package processor
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rtfSkippedDestinations are the RTF groups that hold no prose: tables of
+// fonts, colours and styles, document metadata, pictures, field instructions,
+// headers, footers and annotations
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "expandedcolortbl": true, "stylesheet": true,
+	"listtable": true, "listoverridetable": true, "revtbl": true, "rsidtbl": true,
+	"info": true, "pict": true, "object": true, "fldinst": true, "themedata": true,
+	"colorschememapping": true, "datastore": true, "latentstyles": true, "generator": true,
+	"xmlnstbl": true, "mmathPr": true, "header": true, "headerl": true, "headerr": true,
+	"headerf": true, "footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"annotation": true, "atnid": true, "atnauthor": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// rtfSymbols are control words that stand for a single character
+var rtfSymbols = map[string]string{
+	"tab": " ", "cell": " ", "emdash": "—", "endash": "–", "emspace": " ", "enspace": " ",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”", "bullet": "•",
+}
+
+// rtfParagraphBreaks are control words that end a paragraph
+var rtfParagraphBreaks = map[string]bool{
+	"par": true, "line": true, "sect": true, "page": true, "row": true,
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of the Windows-1252 code page,
+// the default for \'hh escapes, to their characters. Other bytes map to the
+// Latin-1 character of the same value.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// rtfFormat extracts prose from Rich Text Format files
+type rtfFormat struct{}
+
+func (rtfFormat) Name() string { return "rtf" }
+
+func (rtfFormat) Extract(path string, opts Options, doc *Document) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractRTF(file, doc)
+}
+
+// rtfGroup is the state an RTF group inherits from its parent
+type rtfGroup struct {
+	skip bool // Inside a destination that holds no prose
+	uc   int  // Fallback characters that follow each \u character
+}
+
+// rtfReader turns an RTF stream into paragraphs of plain text
+type rtfReader struct {
+	r         *bufio.Reader
+	doc       *Document
+	group     rtfGroup
+	stack     []rtfGroup
+	text      strings.Builder // Text of the current paragraph
+	skipChars int             // Fallback characters still to skip after a \u character
+}
+
+// extractRTF writes the paragraphs of an RTF document to doc
+func extractRTF(r io.Reader, doc *Document) error {
+	reader := &rtfReader{r: bufio.NewReader(r), doc: doc, group: rtfGroup{uc: 1}}
+	if err := reader.read(); err != nil {
+		return err
+	}
+	reader.paragraph()
+	return nil
+}
+
+func (r *rtfReader) read() error {
+	for {
+		c, err := r.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch c {
+		case '{':
+			r.stack = append(r.stack, r.group)
+		case '}':
+			if len(r.stack) > 0 {
+				r.group = r.stack[len(r.stack)-1]
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+		case '\\':
+			if err := r.control(); err != nil {
+				return err
+			}
+		case '\r', '\n':
+		default:
+			r.char(rune(c))
+		}
+	}
+}
+
+// control handles the control word or symbol after a backslash
+func (r *rtfReader) control() error {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return ignoreEOF(err)
+	}
+
+	switch {
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		r.r.UnreadByte()
+		word, param, hasParam, err := r.controlWord()
+		if err != nil {
+			return err
+		}
+		return r.word(word, param, hasParam)
+	case c == '\'':
+		hex := make([]byte, 2)
+		if _, err := io.ReadFull(r.r, hex); err != nil {
+			return ignoreEOF(err)
+		}
+		if b, err := strconv.ParseUint(string(hex), 16, 8); err == nil {
+			r.char(decodeWindows1252(byte(b)))
+		}
+	case c == '*':
+		r.group.skip = true
+	case c == '~':
+		r.char(' ')
+	case c == '_':
+		r.char('-')
+	case c == '-':
+		// Optional hyphen
+	case c == '\r' || c == '\n':
+		r.paragraph()
+	default:
+		r.char(rune(c))
+	}
+
+	return nil
+}
+
+// controlWord reads the letters and optional numeric parameter of a control
+// word, consuming the space that may delimit it
+func (r *rtfReader) controlWord() (string, int, bool, error) {
+	var word, digits []byte
+	for {
+		c, err := r.r.ReadByte()
+		if err != nil {
+			return string(word), 0, false, ignoreEOF(err)
+		}
+
+		switch {
+		case len(digits) == 0 && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'):
+			word = append(word, c)
+			continue
+		case c >= '0' && c <= '9' || c == '-' && len(digits) == 0:
+			digits = append(digits, c)
+			continue
+		case c != ' ':
+			r.r.UnreadByte()
+		}
+
+		param, err := strconv.Atoi(string(digits))
+		return string(word), param, err == nil, nil
+	}
+}
+
+// word applies a control word
+func (r *rtfReader) word(word string, param int, hasParam bool) error {
+	switch {
+	case rtfSkippedDestinations[word]:
+		r.group.skip = true
+	case word == "bin" && hasParam:
+		// Binary data follows the control word
+		_, err := io.CopyN(io.Discard, r.r, int64(param))
+		return ignoreEOF(err)
+	case word == "uc" && hasParam:
+		r.group.uc = param
+	case word == "u" && hasParam:
+		if param < 0 {
+			param += 65536
+		}
+		r.char(rune(param))
+		r.skipChars = r.group.uc
+	case rtfParagraphBreaks[word]:
+		r.paragraph()
+	case rtfSymbols[word] != "":
+		for _, c := range rtfSymbols[word] {
+			r.char(c)
+		}
+	}
+
+	return nil
+}
+
+// char adds a character to the current paragraph, unless it is a fallback
+// for a \u character or inside a skipped destination
+func (r *rtfReader) char(c rune) {
+	if r.skipChars > 0 {
+		r.skipChars--
+		return
+	}
+	if !r.group.skip {
+		r.text.WriteRune(c)
+	}
+}
+
+// paragraph writes the current paragraph to the document
+func (r *rtfReader) paragraph() {
+	r.skipChars = 0
+	if r.group.skip {
+		return
+	}
+	r.doc.Text(r.text.String())
+	r.text.Reset()
+}
+
+// decodeWindows1252 returns the character for a byte in the Windows-1252 code page
+func decodeWindows1252(b byte) rune {
+	if b >= 0x80 && b < 0xA0 {
+		return windows1252[b-0x80]
+	}
+	return rune(b)
+}
+
+// ignoreEOF treats the end of a truncated document as its end
+func ignoreEOF(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
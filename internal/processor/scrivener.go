@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BundleItem is one document inside a bundle
+type BundleItem struct {
+	Name      string // Label of the document within the bundle, e.g. its binder path
+	Path      string // File holding the document's text
+	Extractor Extractor
+}
+
+// Bundle is implemented by extractors for directories that hold a project of
+// several documents, such as a Scrivener .scriv bundle. Each document is
+// counted as an item of its own.
+type Bundle interface {
+	Items(path string) ([]BundleItem, error)
+}
+
+// scrivenerBinder is the part of a .scrivx file that lists the documents
+type scrivenerBinder struct {
+	Items []scrivenerItem `xml:"Binder>BinderItem"`
+}
+
+// scrivenerItem is a document or folder in a Scrivener binder
+type scrivenerItem struct {
+	UUID             string          `xml:"UUID,attr"` // Scrivener 3 identifier
+	ID               string          `xml:"ID,attr"`   // Scrivener 2 identifier
+	Type             string          `xml:"Type,attr"`
+	Title            string          `xml:"Title"`
+	IncludeInCompile string          `xml:"MetaData>IncludeInCompile"`
+	Children         []scrivenerItem `xml:"Children>BinderItem"`
+}
+
+// scrivenerFormat counts the manuscript of a Scrivener project: the documents
+// in its Draft folder that are included in compile
+type scrivenerFormat struct{}
+
+func (scrivenerFormat) Name() string { return "scrivener" }
+
+func (s scrivenerFormat) Extract(path string, opts Options, doc *Document) error {
+	items, err := s.Items(path)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := item.Extractor.Extract(item.Path, opts, doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Items returns the RTF content files of the Draft folder's documents that
+// are included in compile, in binder order
+func (scrivenerFormat) Items(path string) ([]BundleItem, error) {
+	binderPath, err := scrivenerBinderPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(binderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var binder scrivenerBinder
+	if err := xml.Unmarshal(data, &binder); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", binderPath, err)
+	}
+
+	var items []BundleItem
+	names := make(map[string]int)
+	var walk func(item scrivenerItem, parent string)
+	walk = func(item scrivenerItem, parent string) {
+		name := strings.TrimSpace(item.Title)
+		if parent != "" {
+			name = parent + "/" + name
+		}
+
+		if strings.EqualFold(item.IncludeInCompile, "yes") {
+			if content, ok := scrivenerContent(path, item); ok {
+				// Binder titles need not be unique
+				names[name]++
+				label := name
+				if names[name] > 1 {
+					label += " (" + strconv.Itoa(names[name]) + ")"
+				}
+				items = append(items, BundleItem{Name: label, Path: content, Extractor: rtfFormat{}})
+			}
+		}
+
+		for _, child := range item.Children {
+			walk(child, name)
+		}
+	}
+
+	for _, item := range binder.Items {
+		if item.Type == "DraftFolder" {
+			for _, child := range item.Children {
+				walk(child, "")
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// scrivenerBinderPath finds the .scrivx binder inside a .scriv bundle
+func scrivenerBinderPath(bundle string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(bundle, "*.scrivx"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%s has no .scrivx binder", bundle)
+	}
+	return matches[0], nil
+}
+
+// scrivenerContent returns the RTF file holding a binder item's text. Scrivener
+// 3 keeps it at Files/Data/<UUID>/content.rtf and Scrivener 2 at Files/Docs/<ID>.rtf.
+// Items without text, such as empty folders, have no content file.
+func scrivenerContent(bundle string, item scrivenerItem) (string, bool) {
+	var candidates []string
+	if item.UUID != "" {
+		candidates = append(candidates, filepath.Join(bundle, "Files", "Data", item.UUID, "content.rtf"))
+	}
+	if item.ID != "" {
+		candidates = append(candidates, filepath.Join(bundle, "Files", "Docs", item.ID+".rtf"))
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,376 @@
+package processor
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// maxLineBytes bounds how much of a single line is held in memory. Longer
+// lines are processed in pieces.
+const maxLineBytes = 64 * 1024
+
+// maxFrontmatterLines bounds the lines buffered while looking for the end of
+// a frontmatter block. A block that runs longer is treated as content.
+const maxFrontmatterLines = 1000
+
+// Document receives the prose an Extractor pulls out of a single file and
+// writes it, split into counted and excluded text, to the project's tallies.
+// Text reaches the tallies line by line, so only the current line is held in
+// memory.
+type Document struct {
+	prose    *trimWriter
+	excluded *trimWriter
+	filter   exclusionFilter
+	headings func(level int, text string, written bool) // Called at each heading, before its prose is written unless written is set
+	written  bool                                       // Whether a line has been written, so later ones need a separator
+
+	screenplay *screenplayTracker // Gathers the metrics of Fountain files
+	metadata   map[string]any     // Parsed frontmatter, nil when the file has none
+	skipped    bool               // The frontmatter took the file out of the count
+	encoding   FileEncoding       // How the file was read when it was not plain UTF-8
+}
+
+func newDocument(prose, excluded io.Writer, headings func(level int, text string, written bool)) *Document {
+	return &Document{
+		prose:    &trimWriter{w: prose, trim: true},
+		excluded: &trimWriter{w: excluded, trim: true},
+		headings: headings,
+	}
+}
+
+// Metadata returns the frontmatter parsed from the top of the document, or
+// nil if it had none
+func (d *Document) Metadata() map[string]any {
+	return d.metadata
+}
+
+// Text adds a line of plain prose to the document. Exclusion markers in the
+// text are honoured.
+func (d *Document) Text(line string) {
+	d.write(line, nil)
+}
+
+// write adds a line of prose to the document, also writing the counted text
+// to element when it is set
+func (d *Document) write(line string, element io.Writer) {
+	kept, excluded := d.filter.line(line, nil)
+	if element != nil {
+		io.WriteString(element, kept+"\n")
+	}
+	d.writeLine(kept, excluded, false)
+}
+
+// Heading adds a heading to the document, starting a new section when
+// chapters are counted
+func (d *Document) Heading(level int, text string) {
+	if d.headings != nil {
+		d.headings(level, strings.TrimSpace(text), false)
+	}
+	d.Text(text)
+}
+
+// writeLine writes one line of counted and excluded text. continued is set
+// when the line is a further piece of an overlong line.
+func (d *Document) writeLine(kept, excluded string, continued bool) {
+	if d.written && !continued {
+		d.prose.WriteString("\n")
+		d.excluded.WriteString("\n")
+	}
+	d.written = true
+
+	d.prose.WriteString(kept)
+	d.excluded.WriteString(excluded)
+}
+
+// markupStripper removes the markup of a text format one line at a time,
+// carrying whatever state the format needs between lines
+type markupStripper interface {
+	// line returns the prose in a single line of markup
+	line(line string) string
+	// heading returns the level and prose of the heading on the last line,
+	// or a level of 0 if it was not a heading
+	heading() (int, string)
+}
+
+// codeMarker is a markupStripper for a format with code, in which exclusion
+// markers are literal text
+type codeMarker interface {
+	// code returns the byte ranges of the next line that are code, given the
+	// state left by the lines before it
+	code(line string) [][2]int
+}
+
+// titleUnderliner is a markupStripper for a format whose headings can be
+// marked by a line under the title, so that such a heading is only known
+// after its title has been written as prose
+type titleUnderliner interface {
+	// underlined reports whether the heading on the last line was an
+	// underline, with its title on the line before
+	underlined() bool
+}
+
+// lineHolder is a markupStripper that may hold a line back until the next
+// one shows what it was
+type lineHolder interface {
+	// flush returns the prose of a line still held back at the end of the file
+	flush() string
+}
+
+// frontmatter states of a textStream
+const (
+	beforeContent = iota // Only blank lines seen so far
+	inFrontmatter        // Inside an opening ---, +++ or { line
+	inBody               // Frontmatter handled, streaming content
+)
+
+// textStream carries a text file line by line through frontmatter stripping,
+// exclusion markers and markup stripping into a Document. Only the current
+// line and an unclosed frontmatter block are held in memory.
+type textStream struct {
+	doc         *Document
+	markup      markupStripper // Strips the counted text
+	excluded    markupStripper // Strips the excluded text
+	frontmatter bool           // Whether the format may open with frontmatter
+	raw         bool           // Raw Markdown keeps its whitespace after frontmatter
+
+	state    int
+	buffered []string // Lines held back until the frontmatter is resolved
+	format   string   // Format of the open frontmatter
+	opened   int      // Index in buffered of the line that opened the frontmatter
+	depth    int      // Brace depth of open JSON frontmatter
+	inString bool     // Inside a string of open JSON frontmatter
+}
+
+// line handles the next line of the file. continued is set when the line is
+// a further piece of an overlong line.
+func (s *textStream) line(line string, continued bool) {
+	switch s.state {
+	case beforeContent:
+		if !s.frontmatter {
+			s.state = inBody
+			s.write(line, continued)
+			return
+		}
+		if continued || strings.TrimSpace(line) != "" {
+			if format := openingFrontmatter(line); format != "" && !continued {
+				// Raw output keeps its whitespace when the file opens with
+				// frontmatter
+				if len(s.buffered) == 0 && s.raw {
+					s.doc.prose.trim = false
+					s.doc.excluded.trim = false
+				}
+				s.format = format
+				s.opened = len(s.buffered)
+				s.buffered = append(s.buffered, line)
+				s.state = inFrontmatter
+				if format == jsonFrontmatter {
+					s.depth = jsonDepth(line, 0, &s.inString)
+					s.closeJSON()
+				}
+				return
+			}
+			s.flushBuffered()
+			s.write(line, continued)
+			return
+		}
+		s.buffered = append(s.buffered, line)
+
+	case inFrontmatter:
+		switch {
+		case s.format == jsonFrontmatter:
+			s.buffered = append(s.buffered, line)
+			s.depth = jsonDepth(line, s.depth, &s.inString)
+			if s.closeJSON() {
+				return
+			}
+		case closesFrontmatter(s.format, line) && !continued:
+			s.parse(s.buffered[s.opened+1:])
+			return
+		default:
+			s.buffered = append(s.buffered, line)
+		}
+		if len(s.buffered) > maxFrontmatterLines {
+			s.flushBuffered()
+		}
+
+	default:
+		s.write(line, continued)
+	}
+}
+
+// closeJSON ends open JSON frontmatter once its braces balance, reporting
+// whether it did. An object that is not valid JSON is content after all.
+func (s *textStream) closeJSON() bool {
+	if s.depth > 0 {
+		return false
+	}
+	body := strings.Join(s.buffered[s.opened:], "\n")
+	if !json.Valid([]byte(body)) {
+		s.flushBuffered()
+		return true
+	}
+	s.parse(s.buffered[s.opened:])
+	return true
+}
+
+// parse records the metadata in the lines of a closed frontmatter block and
+// moves on to the body. A block that cannot be parsed is still left out of
+// the count.
+func (s *textStream) parse(lines []string) {
+	if metadata, err := parseFrontmatter(s.format, strings.Join(lines, "\n")); err == nil {
+		s.doc.metadata = metadata
+		s.doc.skipped = excludedByMetadata(metadata)
+	}
+	s.buffered = nil
+	s.state = inBody
+}
+
+// close finishes the file, emitting any unclosed frontmatter and any line
+// the strippers still hold back as content
+func (s *textStream) close() {
+	if s.state != inBody {
+		s.flushBuffered()
+	}
+
+	kept, excluded := heldProse(s.markup), heldProse(s.excluded)
+	if (kept != "" || excluded != "") && !s.doc.skipped {
+		s.doc.writeLine(kept, excluded, false)
+	}
+}
+
+// heldProse returns the prose stripper still holds back, if it holds lines
+func heldProse(stripper markupStripper) string {
+	if holder, ok := stripper.(lineHolder); ok {
+		return holder.flush()
+	}
+	return ""
+}
+
+// flushBuffered writes the held back lines as content
+func (s *textStream) flushBuffered() {
+	s.doc.prose.trim = true
+	s.doc.excluded.trim = true
+	s.state = inBody
+
+	for _, line := range s.buffered {
+		s.write(line, false)
+	}
+	s.buffered = nil
+}
+
+// write splits a content line into counted and excluded text and strips the
+// markup from both
+func (s *textStream) write(line string, continued bool) {
+	if s.doc.skipped {
+		return
+	}
+
+	kept, excluded := s.doc.filter.line(line, s.code(line))
+	kept = s.markup.line(kept)
+	excluded = s.excluded.line(excluded)
+
+	if level, text := s.markup.heading(); level > 0 && !continued && s.doc.headings != nil {
+		underliner, ok := s.markup.(titleUnderliner)
+		s.doc.headings(level, strings.TrimSpace(text), ok && underliner.underlined())
+	}
+
+	s.doc.writeLine(kept, excluded, continued)
+}
+
+// code returns the byte ranges of line that are code, as seen by the
+// stripper of the text the line starts in
+func (s *textStream) code(line string) [][2]int {
+	stripper := s.markup
+	if s.doc.filter.off || s.doc.filter.inPercent {
+		stripper = s.excluded
+	}
+	if marker, ok := stripper.(codeMarker); ok {
+		return marker.code(line)
+	}
+	return nil
+}
+
+// extractText streams the text file at path through markup strippers made by
+// newMarkup into doc
+func extractText(path string, doc *Document, newMarkup func() markupStripper, frontmatter, raw bool) error {
+	file, err := openText(path, doc)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stream := &textStream{
+		doc:         doc,
+		markup:      newMarkup(),
+		excluded:    newMarkup(),
+		frontmatter: frontmatter,
+		raw:         raw,
+	}
+	if err := readLines(file, stream.line); err != nil {
+		return err
+	}
+	stream.close()
+
+	return nil
+}
+
+// trimWriter drops leading and trailing whitespace from the text written
+// through it, like strings.TrimSpace on the whole text, holding back
+// whitespace until it knows more text follows
+type trimWriter struct {
+	w       io.Writer
+	trim    bool
+	started bool
+	pending []byte
+}
+
+func (t *trimWriter) WriteString(s string) {
+	if !t.trim {
+		io.WriteString(t.w, s)
+		return
+	}
+
+	if !t.started {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return
+		}
+		t.started = true
+	}
+
+	body := strings.TrimRightFunc(s, unicode.IsSpace)
+	if body != "" {
+		t.w.Write(t.pending)
+		t.pending = t.pending[:0]
+		io.WriteString(t.w, body)
+	}
+	t.pending = append(t.pending, s[len(body):]...)
+}
+
+// readLines calls fn with each line of r without its trailing newline, in
+// the same way strings.Split(content, "\n") would split it. Lines longer than
+// maxLineBytes are passed in pieces, with continued set on all but the first.
+func readLines(r io.Reader, fn func(line string, continued bool)) error {
+	reader := bufio.NewReaderSize(r, maxLineBytes)
+	continued := false
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		switch err {
+		case bufio.ErrBufferFull:
+			fn(string(chunk), continued)
+			continued = true
+		case nil:
+			fn(string(chunk[:len(chunk)-1]), continued)
+			continued = false
+		case io.EOF:
+			fn(string(chunk), continued)
+			return nil
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bwilson/verkounter/internal/counter"
+)
+
+// resolveCollisions sets the key of every project and keeps projects apart
+// that would otherwise share one. A project whose sanitized name another
+// project shares is named after its series as well, e.g. Fantasy/Book-1, or
+// after the folders it sits in when that is not enough. Projects recorded
+// under an ID keep it, unless a copied .verkount file gave the same ID to
+// several projects. Names, keys and notes are worked out afresh on each call.
+func resolveCollisions(folders []VerkountFolder, rootPath string) {
+	byName := make(map[string][]int)
+	byID := make(map[string][]int)
+	for i := range folders {
+		folders[i].Name = displayName(folders[i])
+		folders[i].Collision = ""
+		folders[i].LegacyKey = counter.SanitizeFolderName(folders[i].Name)
+		byName[folders[i].LegacyKey] = append(byName[folders[i].LegacyKey], i)
+		if folders[i].ID != "" {
+			byID[folders[i].ID] = append(byID[folders[i].ID], i)
+		}
+	}
+
+	for name, indexes := range byName {
+		if len(indexes) < 2 {
+			continue
+		}
+		qualified := qualifiedNames(folders, indexes, rootPath)
+		for _, i := range indexes {
+			folders[i].Name = qualified[i]
+			folders[i].Collision = fmt.Sprintf("%d projects are named %s, this one is shown as %s",
+				len(indexes), name, counter.SanitizeFolderName(qualified[i]))
+		}
+	}
+
+	for i := range folders {
+		folders[i].Key = folders[i].ID
+		if folders[i].Key == "" {
+			folders[i].Key = counter.SanitizeFolderName(folders[i].Name)
+		}
+	}
+
+	for id, indexes := range byID {
+		if len(indexes) < 2 {
+			continue
+		}
+		var paths []string
+		for _, i := range indexes {
+			paths = append(paths, folders[i].Path)
+		}
+		sort.Strings(paths)
+		for _, i := range indexes {
+			// None of them can be told apart by the ID, so none keeps it.
+			// Their names are already kept apart.
+			folders[i].Key = counter.SanitizeFolderName(folders[i].Name)
+			folders[i].Collision = fmt.Sprintf("%s share the ID %s, remove the id line from the copied .verkount file; this one is recorded as %s",
+				strings.Join(paths, " and "), id, folders[i].Key)
+		}
+	}
+}
+
+// qualifiedNames names each of the colliding projects after its series, or
+// after the folders between the scanned folder and the project when several
+// share a series. A project without a series, such as the scanned folder
+// itself, keeps its plain name.
+func qualifiedNames(folders []VerkountFolder, indexes []int, rootPath string) map[int]string {
+	names := make(map[int]string)
+	seen := make(map[string]int)
+	for _, i := range indexes {
+		names[i] = folders[i].Name
+		if folders[i].Series != "" {
+			names[i] = folders[i].Series + "/" + folders[i].Name
+		}
+		seen[counter.SanitizeFolderName(names[i])]++
+	}
+
+	for _, i := range indexes {
+		if seen[counter.SanitizeFolderName(names[i])] < 2 {
+			continue
+		}
+		if rel, err := filepath.Rel(rootPath, filepath.Dir(folders[i].Path)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			names[i] = filepath.ToSlash(rel) + "/" + folders[i].Name
+		}
+	}
+	return names
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveCollisions(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "home", "writer", "Documents")
+
+	type want struct {
+		name      string
+		key       string
+		legacyKey string
+		collision string // Text the note contains, empty when there should be none
+	}
+	tests := []struct {
+		name    string
+		folders []VerkountFolder
+		want    []want
+	}{
+		{
+			name: "Different names",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Book One"), Series: "Fantasy"},
+				{Path: filepath.Join(root, "Fantasy", "Book Two"), Series: "Fantasy", ID: "a1", Config: ProjectConfig{ID: "a1"}},
+			},
+			want: []want{
+				{name: "Book One", key: "Book-One", legacyKey: "Book-One"},
+				{name: "Book Two", key: "a1", legacyKey: "Book-Two"},
+			},
+		},
+		{
+			name: "Same name in different series",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Book 1"), Series: "Fantasy"},
+				{Path: filepath.Join(root, "SciFi", "Book 1"), Series: "SciFi", ID: "b2", Config: ProjectConfig{ID: "b2"}},
+			},
+			want: []want{
+				{name: "Fantasy/Book 1", key: "Fantasy/Book-1", legacyKey: "Book-1", collision: "2 projects are named Book-1, this one is shown as Fantasy/Book-1"},
+				{name: "SciFi/Book 1", key: "b2", legacyKey: "Book-1", collision: "this one is shown as SciFi/Book-1"},
+			},
+		},
+		{
+			name: "Same name in the same series",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Book"), Series: "Fantasy"},
+				{Path: filepath.Join(root, "Fantasy", "Drafts", "Book"), Series: "Fantasy"},
+			},
+			want: []want{
+				{name: "Fantasy/Book", key: "Fantasy/Book", legacyKey: "Book", collision: "shown as Fantasy/Book"},
+				{name: "Fantasy/Drafts/Book", key: "Fantasy/Drafts/Book", legacyKey: "Book", collision: "shown as Fantasy/Drafts/Book"},
+			},
+		},
+		{
+			name: "Name set in the .verkount file",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Untitled"), Series: "Fantasy", Config: ProjectConfig{Name: "Book"}},
+				{Path: filepath.Join(root, "SciFi", "Book"), Series: "SciFi"},
+			},
+			want: []want{
+				{name: "Fantasy/Book", key: "Fantasy/Book", legacyKey: "Book", collision: "shown as Fantasy/Book"},
+				{name: "SciFi/Book", key: "SciFi/Book", legacyKey: "Book", collision: "shown as SciFi/Book"},
+			},
+		},
+		{
+			name: "Project at the root of the scan",
+			folders: []VerkountFolder{
+				{Path: root, Config: ProjectConfig{Name: "Book"}},
+				{Path: filepath.Join(root, "Fantasy", "Book"), Series: "Fantasy"},
+			},
+			want: []want{
+				{name: "Book", key: "Book", legacyKey: "Book", collision: "shown as Book"},
+				{name: "Fantasy/Book", key: "Fantasy/Book", legacyKey: "Book", collision: "shown as Fantasy/Book"},
+			},
+		},
+		{
+			name: "Duplicate IDs",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Book"), Series: "Fantasy", ID: "c3", Config: ProjectConfig{ID: "c3"}},
+				{Path: filepath.Join(root, "Fantasy", "Sequel"), Series: "Fantasy", ID: "c3", Config: ProjectConfig{ID: "c3"}},
+			},
+			want: []want{
+				{name: "Book", key: "Book", legacyKey: "Book", collision: "share the ID c3"},
+				{name: "Sequel", key: "Sequel", legacyKey: "Sequel", collision: "this one is recorded as Sequel"},
+			},
+		},
+		{
+			name: "Duplicate IDs and names",
+			folders: []VerkountFolder{
+				{Path: filepath.Join(root, "Fantasy", "Book"), Series: "Fantasy", ID: "d4", Config: ProjectConfig{ID: "d4"}},
+				{Path: filepath.Join(root, "SciFi", "Book"), Series: "SciFi", ID: "d4", Config: ProjectConfig{ID: "d4"}},
+			},
+			want: []want{
+				{name: "Fantasy/Book", key: "Fantasy/Book", legacyKey: "Book", collision: "recorded as Fantasy/Book"},
+				{name: "SciFi/Book", key: "SciFi/Book", legacyKey: "Book", collision: "recorded as SciFi/Book"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folders := tt.folders
+			resolveCollisions(folders, root)
+
+			for i, w := range tt.want {
+				f := folders[i]
+				if f.Name != w.name || f.Key != w.key || f.LegacyKey != w.legacyKey {
+					t.Errorf("folder %d: Name = %q, Key = %q, LegacyKey = %q, want %q, %q, %q",
+						i, f.Name, f.Key, f.LegacyKey, w.name, w.key, w.legacyKey)
+				}
+				if (w.collision == "" && f.Collision != "") || !strings.Contains(f.Collision, w.collision) {
+					t.Errorf("folder %d: Collision = %q, want it to contain %q", i, f.Collision, w.collision)
+				}
+			}
+
+			// Resolving again, as after projects are given IDs, changes nothing
+			again := make([]VerkountFolder, len(folders))
+			copy(again, folders)
+			resolveCollisions(again, root)
+			if !reflect.DeepEqual(again, folders) {
+				t.Errorf("second call gave %+v, want %+v", again, folders)
+			}
+		})
+	}
+}
+
+func TestQualifiedNames(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "books")
+	folders := []VerkountFolder{
+		{Path: root, Name: "Book"},
+		{Path: filepath.Join(root, "Fantasy", "Book"), Name: "Book", Series: "Fantasy"},
+		{Path: filepath.Join(root, "Fantasy", "Old", "Book"), Name: "Book", Series: "Fantasy"},
+		{Path: filepath.Join(root, "Elsewhere", "Book"), Name: "Book", Series: "Fantasy"},
+	}
+
+	names := qualifiedNames(folders, []int{0, 1, 2, 3}, root)
+	want := map[int]string{
+		0: "Book",
+		1: "Fantasy/Book",
+		2: "Fantasy/Old/Book",
+		3: "Elsewhere/Book",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("qualifiedNames() = %v, want %v", names, want)
+	}
+}
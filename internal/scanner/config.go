@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfig holds the optional settings stored in a project's .verkount file.
+// An empty marker file yields the zero value.
+type ProjectConfig struct {
+	ID       string   `yaml:"id"`       // Identifies the project in its history, written on the first scan
+	Name     string   `yaml:"name"`     // Display name, instead of the folder's name
+	Series   string   `yaml:"series"`   // Series the project belongs to, instead of the folder it sits in
+	Target   int      `yaml:"target"`   // Word count the project aims for
+	Deadline Date     `yaml:"deadline"` // Day the target is due (e.g. 2026-03-31)
+	Status   string   `yaml:"status"`   // Where the project stands (e.g. drafting, revising)
+	Tags     []string `yaml:"tags"`     // Labels for the project (e.g. [thriller, nanowrimo])
+
+	Counter           string   `yaml:"counter"`             // Counting strategy for this project (e.g. unicode-words)
+	CharactersPerWord int      `yaml:"characters_per_word"` // Ratio used by the heuristic strategy
+	FileHistory       bool     `yaml:"file_history"`        // Record per-file word counts
+	ChapterLevel      int      `yaml:"chapter_level"`       // Count chapters split at headings up to this level
+	Extensions        []string `yaml:"extensions"`          // File extensions to count (e.g. [md, org, txt])
+	Include           []string `yaml:"include"`             // Globs of the files to count (e.g. [chapters/**])
+	Exclude           []string `yaml:"exclude"`             // Globs of the files and folders to leave out, as in .verkountignore
+
+	MaxFileSize string     `yaml:"max_file_size"` // Skip files larger than this (e.g. 20MB), 0 for no limit
+	Categories  Categories `yaml:"categories"`    // Globs of the files in each category (e.g. notes: [notes/**])
+
+	Markdown MarkdownConfig `yaml:"markdown"` // Which Markdown constructs count as prose
+	Office   OfficeConfig   `yaml:"office"`   // Which parts of .docx and .odt documents count as prose
+	LaTeX    LaTeXConfig    `yaml:"latex"`    // Which parts of .tex documents count as prose
+}
+
+// MarkdownConfig holds the markdown section of a .verkount file
+type MarkdownConfig struct {
+	Raw               bool `yaml:"raw"`                 // Count the raw Markdown source
+	CountCodeBlocks   bool `yaml:"count_code_blocks"`   // Count the contents of fenced code blocks
+	CountInlineCode   bool `yaml:"count_inline_code"`   // Count the contents of `inline code` spans
+	CountAltText      bool `yaml:"count_alt_text"`      // Count image alt text
+	CountHTMLComments bool `yaml:"count_html_comments"` // Count the text inside <!-- comments -->
+	SkipTables        bool `yaml:"skip_tables"`         // Leave table cells out of the count
+}
+
+// OfficeConfig holds the office section of a .verkount file
+type OfficeConfig struct {
+	HeadersFooters bool `yaml:"headers_footers"` // Count page headers and footers
+}
+
+// LaTeXConfig holds the latex section of a .verkount file
+type LaTeXConfig struct {
+	SkipBibliography bool `yaml:"skip_bibliography"` // Leave the thebibliography environment out of the count
+	SkipCaptions     bool `yaml:"skip_captions"`     // Leave figure and table captions out of the count
+}
+
+// Category names a part of a project, such as its notes or research, by
+// globs of its files and folders in .gitignore syntax
+type Category struct {
+	Name  string
+	Globs []string
+}
+
+// Categories are a project's categories in the order they are written. A
+// file belongs to the first category whose globs match it.
+type Categories []Category
+
+// UnmarshalYAML reads categories from a mapping of names to globs, keeping
+// the order they are written in
+func (c *Categories) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: categories must map names to lists of globs", value.Line)
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		name, globsNode := value.Content[i], value.Content[i+1]
+
+		var globs []string
+		if globsNode.Kind == yaml.ScalarNode {
+			globs = []string{globsNode.Value}
+		} else if err := globsNode.Decode(&globs); err != nil {
+			return fmt.Errorf("line %d: globs of category %s: %v", globsNode.Line, name.Value, err)
+		}
+		*c = append(*c, Category{Name: name.Value, Globs: globs})
+	}
+	return nil
+}
+
+// Date is a calendar day written as YYYY-MM-DD. The zero value means no date.
+type Date struct {
+	time.Time
+}
+
+// UnmarshalYAML parses a day given in a .verkount file
+func (d *Date) UnmarshalYAML(value *yaml.Node) error {
+	day, err := time.ParseInLocation("2006-01-02", value.Value, time.Local)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid date %q, want YYYY-MM-DD", value.Line, value.Value)
+	}
+	d.Time = day
+	return nil
+}
+
+// loadProjectConfig reads the .verkount file at path. Files that are empty or
+// do not contain a YAML mapping are treated as plain markers.
+func loadProjectConfig(path string) (ProjectConfig, error) {
+	var config ProjectConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return config, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return config, nil
+	}
+
+	if err := doc.Content[0].Decode(&config); err != nil {
+		return config, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	return config, nil
+}
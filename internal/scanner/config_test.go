@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadProjectConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected ProjectConfig
+		err      string // Text the error contains, empty when there should be none
+	}{
+		{
+			name:    "Empty marker",
+			content: "",
+		},
+		{
+			name:    "Comments only",
+			content: "# Counted by verkounter\n",
+		},
+		{
+			name:    "Scalar marker",
+			content: "track this folder\n",
+		},
+		{
+			name:    "Project details",
+			content: "id: 3f9c2a71d04b8e65\nname: The Long Road\nseries: Road Trilogy\ntarget: 90000\ndeadline: 2026-03-31\nstatus: drafting\ntags: [thriller, nanowrimo]\n",
+			expected: ProjectConfig{
+				ID:       "3f9c2a71d04b8e65",
+				Name:     "The Long Road",
+				Series:   "Road Trilogy",
+				Target:   90000,
+				Deadline: Date{time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local)},
+				Status:   "drafting",
+				Tags:     []string{"thriller", "nanowrimo"},
+			},
+		},
+		{
+			name:    "Invalid deadline",
+			content: "target: 90000\ndeadline: 2026-13-01\n",
+			err:     `line 2: invalid date "2026-13-01", want YYYY-MM-DD`,
+		},
+		{
+			name:    "Processing settings",
+			content: "max_file_size: 20MB\ncategories:\n  notes: notes/**\nmarkdown:\n  count_code_blocks: true\noffice:\n  headers_footers: true\nlatex:\n  skip_captions: true\n",
+			expected: ProjectConfig{
+				MaxFileSize: "20MB",
+				Categories:  Categories{{Name: "notes", Globs: []string{"notes/**"}}},
+				Markdown:    MarkdownConfig{CountCodeBlocks: true},
+				Office:      OfficeConfig{HeadersFooters: true},
+				LaTeX:       LaTeXConfig{SkipCaptions: true},
+			},
+		},
+		{
+			name:     "Size without a unit",
+			content:  "max_file_size: 0\n",
+			expected: ProjectConfig{MaxFileSize: "0"},
+		},
+		{
+			name:    "Invalid YAML",
+			content: "target: [90000\n",
+			err:     "could not parse",
+		},
+		{
+			name:    "Wrong type",
+			content: "target: lots\n",
+			err:     "could not parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".verkount")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create marker: %v", err)
+			}
+
+			config, err := loadProjectConfig(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("loadProjectConfig() error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadProjectConfig failed: %v", err)
+			}
+			if !reflect.DeepEqual(config, tt.expected) {
+				t.Errorf("loadProjectConfig() = %+v, want %+v", config, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDateUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{value: "2026-03-31", expected: time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local), valid: true},
+		{value: `"2026-03-31"`, expected: time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local), valid: true},
+		{value: "2026-02-30"},
+		{value: "2026-13-01"},
+		{value: "31/03/2026"},
+		{value: "2026-03-31T12:00:00Z"},
+		{value: "tomorrow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var config struct {
+				Deadline Date `yaml:"deadline"`
+			}
+			err := yaml.Unmarshal([]byte("deadline: "+tt.value), &config)
+			if !tt.valid {
+				if err == nil || !strings.Contains(err.Error(), "want YYYY-MM-DD") {
+					t.Errorf("error = %v, want an invalid date", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !config.Deadline.Equal(tt.expected) {
+				t.Errorf("Deadline = %v, want %v", config.Deadline, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCategoriesUnmarshalYAML(t *testing.T) {
+	var categories Categories
+	input := "research: [research/, \"*.bib.md\"]\nnotes: notes/**\nmanuscript: []\n"
+	if err := yaml.Unmarshal([]byte(input), &categories); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := Categories{
+		{Name: "research", Globs: []string{"research/", "*.bib.md"}},
+		{Name: "notes", Globs: []string{"notes/**"}},
+		{Name: "manuscript", Globs: []string{}},
+	}
+	if !reflect.DeepEqual(categories, want) {
+		t.Errorf("Categories = %+v, want %+v", categories, want)
+	}
+
+	if err := yaml.Unmarshal([]byte("- notes/\n"), &categories); err == nil {
+		t.Error("Unmarshal of a list succeeded, want an error")
+	}
+}
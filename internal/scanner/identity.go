@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newProjectID returns a random identifier for a project
+func newProjectID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// AssignProjectIDs gives every marked project in folders that has no ID yet a
+// new one, written to its .verkount file, and records the project under it.
+// A project whose marker cannot be written, e.g. on a read-only volume, keeps
+// its history under its name; the errors for those are returned.
+func AssignProjectIDs(folders []VerkountFolder, rootPath string) []error {
+	var errs []error
+	for i := range folders {
+		folder := &folders[i]
+		if folder.ID != "" || folder.Err != nil {
+			continue
+		}
+		marker := filepath.Join(folder.Path, ".verkount")
+		if _, err := os.Stat(marker); err != nil {
+			// A Scrivener project tracked without a marker
+			continue
+		}
+		if err := ensureProjectID(marker, &folder.Config); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", marker, err))
+			continue
+		}
+		folder.ID = folder.Config.ID
+	}
+
+	resolveCollisions(folders, rootPath)
+	return errs
+}
+
+// ensureProjectID gives a project without an ID a new one, appending it to
+// the .verkount file at path so that it survives renames. Comments and
+// settings in the file are kept. A marker holding something other than a
+// YAML mapping is left alone, and the project stays without an ID.
+func ensureProjectID(path string, config *ProjectConfig) error {
+	if config.ID != "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) > 0 && doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	if len(doc.Content) > 0 && doc.Content[0].Style == yaml.FlowStyle {
+		// A mapping written in braces cannot be extended by a line
+		return nil
+	}
+
+	id, err := newProjectID()
+	if err != nil {
+		return err
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, fmt.Sprintf("id: %s # Identifies the project in its history, keep it when renaming\n", id)...)
+	if err := os.WriteFile(path, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	config.ID = id
+	return nil
+}
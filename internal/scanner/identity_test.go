@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var idLinePattern = regexp.MustCompile(`^id: [0-9a-f]{16} # Identifies the project in its history, keep it when renaming\n$`)
+
+func TestEnsureProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		kept    string // Content the ID line is appended to, empty when no ID is given
+		wantID  bool
+	}{
+		{
+			name:    "Empty marker",
+			content: "",
+			kept:    "",
+			wantID:  true,
+		},
+		{
+			name:    "Existing comments and settings",
+			content: "# Settings for the novel\nname: Book One # Shown in reports\ntarget: 90000\n",
+			kept:    "# Settings for the novel\nname: Book One # Shown in reports\ntarget: 90000\n",
+			wantID:  true,
+		},
+		{
+			name:    "No trailing newline",
+			content: "name: Book One",
+			kept:    "name: Book One\n",
+			wantID:  true,
+		},
+		{
+			name:    "Flow style mapping",
+			content: "{name: Book One, target: 90000}\n",
+		},
+		{
+			name:    "Scalar marker",
+			content: "track this folder\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".verkount")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to create marker: %v", err)
+			}
+
+			config, err := loadProjectConfig(path)
+			if err != nil {
+				t.Fatalf("loadProjectConfig failed: %v", err)
+			}
+			if err := ensureProjectID(path, &config); err != nil {
+				t.Fatalf("ensureProjectID failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read marker: %v", err)
+			}
+			content := string(data)
+
+			if !tt.wantID {
+				if config.ID != "" || content != tt.content {
+					t.Errorf("ID = %q, content = %q, want the marker left alone", config.ID, content)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(content, tt.kept) || !idLinePattern.MatchString(content[len(tt.kept):]) {
+				t.Fatalf("content = %q, want %q followed by an id line", content, tt.kept)
+			}
+			if info, err := os.Stat(path); err != nil {
+				t.Fatalf("Failed to stat marker: %v", err)
+			} else if info.Mode().Perm() != 0600 {
+				t.Errorf("marker mode = %v, want 0600", info.Mode().Perm())
+			}
+
+			reloaded, err := loadProjectConfig(path)
+			if err != nil {
+				t.Fatalf("loadProjectConfig failed after the ID was added: %v", err)
+			}
+			if config.ID == "" || reloaded.ID != config.ID {
+				t.Errorf("ID = %q, reloaded as %q", config.ID, reloaded.ID)
+			}
+			if reloaded.Name != config.Name || reloaded.Target != config.Target {
+				t.Errorf("settings changed: %+v, was %+v", reloaded, config)
+			}
+
+			// A project that has an ID keeps it
+			if err := ensureProjectID(path, &reloaded); err != nil {
+				t.Fatalf("ensureProjectID failed: %v", err)
+			}
+			if again, _ := os.ReadFile(path); string(again) != content {
+				t.Errorf("content = %q after a second call, want %q", again, content)
+			}
+		})
+	}
+}
+
+func TestAssignProjectIDs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"Fantasy/Book", "Notes.scriv"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	marker := filepath.Join(root, "Fantasy", "Book", ".verkount")
+	if err := os.WriteFile(marker, []byte("target: 1000\n"), 0644); err != nil {
+		t.Fatalf("Failed to create marker: %v", err)
+	}
+
+	folders, err := ScanForVerkountFolders(root)
+	if err != nil {
+		t.Fatalf("ScanForVerkountFolders failed: %v", err)
+	}
+	if data, _ := os.ReadFile(marker); string(data) != "target: 1000\n" {
+		t.Fatalf("scanning wrote to the marker: %q", data)
+	}
+	if len(folders) != 2 {
+		t.Fatalf("found %d folders, want 2", len(folders))
+	}
+
+	if errs := AssignProjectIDs(folders, root); len(errs) > 0 {
+		t.Fatalf("AssignProjectIDs failed: %v", errs)
+	}
+	for _, folder := range folders {
+		switch folder.Name {
+		case "Book":
+			if folder.ID == "" || folder.Key != folder.ID || folder.LegacyKey != "Book" {
+				t.Errorf("Book: ID = %q, Key = %q, LegacyKey = %q, want a new ID as the key", folder.ID, folder.Key, folder.LegacyKey)
+			}
+		case "Notes":
+			if folder.ID != "" || folder.Key != "Notes" {
+				t.Errorf("Notes: ID = %q, Key = %q, want no ID for a Scrivener project without a marker", folder.ID, folder.Key)
+			}
+		default:
+			t.Errorf("unexpected project %q", folder.Name)
+		}
+	}
+
+	// A marker that cannot be read or written is reported, and the project
+	// keeps its name as its key
+	broken := filepath.Join(root, "Fantasy", "Sequel")
+	if err := os.MkdirAll(filepath.Join(broken, ".verkount"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	folders = []VerkountFolder{{Path: broken, Series: "Fantasy"}}
+	if errs := AssignProjectIDs(folders, root); len(errs) != 1 {
+		t.Fatalf("AssignProjectIDs returned %d errors, want 1", len(errs))
+	}
+	if folders[0].ID != "" || folders[0].Key != "Sequel" {
+		t.Errorf("ID = %q, Key = %q, want the project kept under its name", folders[0].ID, folders[0].Key)
+	}
+}
@@ -3,16 +3,26 @@ package scanner
 import (
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/bwilson/verkounter/internal/ignore"
 )
 
 type VerkountFolder struct {
-	Path   string
-	Name   string
-	Series string // Name of the series folder (direct child of ~/Documents)
+	Path      string
+	ID        string        // Identifier from the .verkount file, empty for projects without one
+	Key       string        // Name the project's history is recorded under: its ID, or its sanitized name
+	LegacyKey string        // Sanitized name of the project, under which history was recorded before it had an ID
+	Name      string        // Display name, from the .verkount file or the folder, qualified when projects share it
+	Series    string        // Name of the series folder (direct child of ~/Documents), unless the .verkount file names one
+	Config    ProjectConfig // Settings read from the .verkount file
+	Err       error         // Set when the .verkount or .verkountignore file could not be parsed
+	Collision string        // Why the project is recorded under a qualified name, when it shares its name or ID
 }
 
 func ScanForVerkountFolders(rootPath string) ([]VerkountFolder, error) {
 	var folders []VerkountFolder
+	rules := make(map[string]*ignore.Rules) // Ignore rules of the projects found so far, by path
 
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -20,17 +30,46 @@ func ScanForVerkountFolders(rootPath string) ([]VerkountFolder, error) {
 		}
 
 		if info.IsDir() {
+			// Folders a project ignores hold neither its files nor other projects
+			if ignoredByProject(path, folders, rules) {
+				return filepath.SkipDir
+			}
+
+			isScrivener := strings.EqualFold(filepath.Ext(path), ".scriv")
 			verkountPath := filepath.Join(path, ".verkount")
 			if _, err := os.Stat(verkountPath); err == nil {
 				// Determine the series name (direct child of rootPath)
 				seriesName := getSeriesName(path, rootPath)
+				config, configErr := loadProjectConfig(verkountPath)
+				if config.Series != "" {
+					seriesName = config.Series
+				}
+				projectRules, rulesErr := ignore.Load(path, config.Include, config.Exclude)
+				if configErr == nil {
+					configErr = rulesErr
+				}
+				rules[path] = projectRules
 
 				folders = append(folders, VerkountFolder{
 					Path:   path,
-					Name:   filepath.Base(path),
+					ID:     config.ID,
 					Series: seriesName,
+					Config: config,
+					Err:    configErr,
+				})
+			} else if isScrivener && !insideProject(path, folders) {
+				// A Scrivener project is tracked without a marker, unless it
+				// belongs to a marked project that already counts it
+				folders = append(folders, VerkountFolder{
+					Path:   path,
+					Series: getSeriesName(path, rootPath),
 				})
 			}
+
+			// The processor reads a Scrivener bundle through its binder
+			if isScrivener {
+				return filepath.SkipDir
+			}
 		}
 
 		return nil
@@ -40,9 +79,55 @@ func ScanForVerkountFolders(rootPath string) ([]VerkountFolder, error) {
 		return nil, err
 	}
 
+	resolveCollisions(folders, rootPath)
+
 	return folders, nil
 }
 
+// displayName returns the name of the project in folder before collisions
+// are resolved: the name from its .verkount file, or else its folder's name
+func displayName(folder VerkountFolder) string {
+	if folder.Config.Name != "" {
+		return folder.Config.Name
+	}
+	return projectName(folder.Path)
+}
+
+// projectName returns the name of the project in folderPath, without the
+// .scriv extension of a Scrivener bundle
+func projectName(folderPath string) string {
+	name := filepath.Base(folderPath)
+	if strings.EqualFold(filepath.Ext(name), ".scriv") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name
+}
+
+// insideProject reports whether path lies within one of the folders found so far
+func insideProject(path string, folders []VerkountFolder) bool {
+	for _, folder := range folders {
+		if rel, err := filepath.Rel(folder.Path, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredByProject reports whether path lies within one of the folders found
+// so far and is ignored by its rules
+func ignoredByProject(path string, folders []VerkountFolder, rules map[string]*ignore.Rules) bool {
+	for _, folder := range folders {
+		rel, err := filepath.Rel(folder.Path, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rules[folder.Path].Ignored(filepath.ToSlash(rel), true) {
+			return true
+		}
+	}
+	return false
+}
+
 // getSeriesName extracts the name of the series folder (direct child of rootPath)
 func getSeriesName(folderPath, rootPath string) string {
 	relPath, err := filepath.Rel(rootPath, folderPath)
@@ -61,7 +146,8 @@ func getSeriesName(folderPath, rootPath string) string {
 	for {
 		parent := filepath.Dir(dir)
 		if parent == "." || parent == "/" || parent == dir {
-			return dir
+			// A Scrivener bundle directly in rootPath is named like its project
+			return projectName(dir)
 		}
 		dir = parent
 	}
@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BreakdownStats holds a project's words per value of the status, pov and
+// tags frontmatter keys
+type BreakdownStats struct {
+	Status map[string]int `yaml:"status,omitempty"`
+	POV    map[string]int `yaml:"pov,omitempty"`
+	Tags   map[string]int `yaml:"tags,omitempty"`
+}
+
+// showBreakdownStats displays the latest words per revision status, POV
+// character and tag of every project whose files set them
+func showBreakdownStats(stats StatsFile) {
+	var latestDate string
+	for date, dayStats := range stats {
+		if len(dayStats.Breakdowns) > 0 && date > latestDate {
+			latestDate = date
+		}
+	}
+	if latestDate == "" {
+		return
+	}
+
+	latest := stats[latestDate].Breakdowns
+	var projects []string
+	for project := range latest {
+		projects = append(projects, project)
+	}
+	sortByName(stats, projects)
+
+	fmt.Println("\nBy Status, POV and Tag:")
+	for _, project := range projects {
+		breakdown := latest[project]
+		fmt.Printf("  %s:\n", projectName(stats, project))
+		showDimension("Status", breakdown.Status)
+		showDimension("POV", breakdown.POV)
+		showDimension("Tags", breakdown.Tags)
+	}
+}
+
+// showDimension prints the words per value of one dimension, largest first
+func showDimension(label string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	var values []string
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprintf("%s %d", value, counts[value])
+	}
+	fmt.Printf("    %s: %s\n", label, strings.Join(parts, ", "))
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// manuscriptCategory is the category of the files that no configured
+// category claims
+const manuscriptCategory = "manuscript"
+
+// usesCategories reports whether any project sorts its files into categories
+// other than the manuscript
+func usesCategories(stats StatsFile) bool {
+	for _, dayStats := range stats {
+		for category := range dayStats.Categories {
+			if category != manuscriptCategory {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// calculateCategoryDeltas returns the words written in one category each day.
+// Days recorded before categories were tracked are left out, and the first
+// day that tracks them is a baseline.
+func calculateCategoryDeltas(stats StatsFile, category string) map[string]int {
+	deltas := make(map[string]int)
+	for date, dayStats := range stats {
+		if dayStats.Categories == nil {
+			continue
+		}
+		deltas[date] = dayStats.CategoryDeltas[category]
+	}
+	return deltas
+}
+
+// showManuscriptWords prints the manuscript words written between start and
+// end, when projects track categories
+func showManuscriptWords(deltas map[string]int, start, end time.Time) {
+	if deltas == nil {
+		return
+	}
+	fmt.Printf("  Manuscript words: %d\n", calculatePeriodStatsFromDeltas(deltas, start, end).total)
+}
+
+// showCategoryStats displays the latest words in each category, with the
+// words written in it this week and over the past 30 days
+func showCategoryStats(stats StatsFile, now time.Time) {
+	var latestDate string
+	for date, dayStats := range stats {
+		if dayStats.Categories != nil && date > latestDate {
+			latestDate = date
+		}
+	}
+	if latestDate == "" {
+		return
+	}
+
+	latest := stats[latestDate].Categories
+	var categories []string
+	for category := range latest {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	weekStart, weekEnd := getCurrentWeekRange(now)
+	thirtyDaysAgo := now.AddDate(0, 0, -29)
+
+	fmt.Println("\nBy Category:")
+	for _, category := range categories {
+		deltas := calculateCategoryDeltas(stats, category)
+		fmt.Printf("  %s: %d words (this week %+d, past 30 days %+d)\n", category, latest[category],
+			calculatePeriodStatsFromDeltas(deltas, weekStart, weekEnd).total,
+			calculatePeriodStatsFromDeltas(deltas, thirtyDaysAgo, now).total)
+	}
+}
@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalculateCategoryDeltas(t *testing.T) {
+	stats := StatsFile{
+		// Written before categories were recorded
+		"2026-01-01": {Projects: map[string]int{"Book": 1000}, Total: 1000},
+		// The first day with categories is a baseline
+		"2026-01-02": {Projects: map[string]int{"Book": 1500}, Total: 1500, Delta: 500,
+			Categories: map[string]int{"manuscript": 1200, "notes": 300}},
+		"2026-01-03": {Projects: map[string]int{"Book": 1650}, Total: 1650, Delta: 150,
+			Categories: map[string]int{"manuscript": 1250, "notes": 400}, CategoryDeltas: map[string]int{"manuscript": 50, "notes": 100}},
+		"2026-01-04": {Projects: map[string]int{"Book": 1600}, Total: 1600, Delta: -50,
+			Categories: map[string]int{"manuscript": 1250, "notes": 350}, CategoryDeltas: map[string]int{"notes": -50}},
+	}
+
+	tests := []struct {
+		category string
+		expected map[string]int
+	}{
+		{"manuscript", map[string]int{"2026-01-02": 0, "2026-01-03": 50, "2026-01-04": 0}},
+		{"notes", map[string]int{"2026-01-02": 0, "2026-01-03": 100, "2026-01-04": -50}},
+		{"research", map[string]int{"2026-01-02": 0, "2026-01-03": 0, "2026-01-04": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := calculateCategoryDeltas(stats, tt.category); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("calculateCategoryDeltas() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestManuscriptWordsApartFromTotal(t *testing.T) {
+	stats := StatsFile{
+		"2026-01-01": {Projects: map[string]int{"Book": 1000}, Total: 1000,
+			Categories: map[string]int{"manuscript": 800, "notes": 200}},
+		// Notes written, and manuscript words cut
+		"2026-01-02": {Projects: map[string]int{"Book": 1100}, Total: 1100, Delta: 100,
+			Categories: map[string]int{"manuscript": 750, "notes": 350}, CategoryDeltas: map[string]int{"manuscript": -50, "notes": 150}},
+		"2026-01-03": {Projects: map[string]int{"Book": 1400}, Total: 1400, Delta: 300,
+			Categories: map[string]int{"manuscript": 1000, "notes": 400}, CategoryDeltas: map[string]int{"manuscript": 250, "notes": 50}},
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2026, 1, 3, 0, 0, 0, 0, time.Local)
+
+	total := calculatePeriodStatsFromDeltas(calculateDailyDeltas(stats), start, end)
+	if total.total != 400 || total.daysWithWriting != 2 {
+		t.Errorf("total = %d words on %d days, want 400 words on 2 days", total.total, total.daysWithWriting)
+	}
+
+	manuscript := calculatePeriodStatsFromDeltas(calculateCategoryDeltas(stats, manuscriptCategory), start, end)
+	if manuscript.total != 200 || manuscript.daysWithWriting != 1 {
+		t.Errorf("manuscript = %d words on %d days, want 200 words on 1 day", manuscript.total, manuscript.daysWithWriting)
+	}
+}
+
+func TestUsesCategories(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    StatsFile
+		expected bool
+	}{
+		{
+			name:  "No categories",
+			stats: StatsFile{"2026-01-01": {Projects: map[string]int{"Book": 100}, Total: 100}},
+		},
+		{
+			name:  "Only the manuscript",
+			stats: StatsFile{"2026-01-01": {Total: 100, Categories: map[string]int{"manuscript": 100}}},
+		},
+		{
+			name: "Notes on an earlier day",
+			stats: StatsFile{
+				"2026-01-01": {Total: 100, Categories: map[string]int{"manuscript": 80, "notes": 20}},
+				"2026-01-02": {Total: 100, Categories: map[string]int{"manuscript": 100}},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usesCategories(tt.stats); got != tt.expected {
+				t.Errorf("usesCategories() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
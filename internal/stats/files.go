@@ -0,0 +1,238 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileDayStats holds a project's per-file word counts for one day
+type FileDayStats struct {
+	Name     string         `yaml:"name,omitempty"`
+	Files    map[string]int `yaml:"files"`
+	Total    int            `yaml:"total"`
+	Chapters []ChapterCount `yaml:"chapters,omitempty"`
+}
+
+// ChapterCount is the word count of one chapter or section
+type ChapterCount struct {
+	Label string `yaml:"label"`
+	Words int    `yaml:"words"`
+}
+
+type FileStatsFile map[string]FileDayStats
+
+// fileChange is the change in a single file's word count
+type fileChange struct {
+	file  string
+	words int
+	delta int
+}
+
+// LoadFileStats loads the per-file history of a project from the XDG data
+// directory, by the key it is recorded under or by the project's name
+func LoadFileStats(project string) (FileStatsFile, error) {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not get data directory: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, "files", strings.ReplaceAll(project, "/", "_")+"_files.yaml"))
+	if os.IsNotExist(err) {
+		// A project with an ID keeps its history under the ID
+		if key := fileHistoryKey(dataDir, project); key != "" {
+			data, err = os.ReadFile(filepath.Join(dataDir, "files", key+"_files.yaml"))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read file history for %s: %v", project, err)
+	}
+
+	var fileStats FileStatsFile
+	if err := yaml.Unmarshal(data, &fileStats); err != nil {
+		return nil, fmt.Errorf("could not parse file history for %s: %v", project, err)
+	}
+
+	return fileStats, nil
+}
+
+// ShowFileStats displays the current size of every file in a project and
+// how much each changed in the most recent entry
+func ShowFileStats(project string, fileStats FileStatsFile) {
+	date, changes := latestFileChanges(fileStats)
+
+	fmt.Printf("\n=== Files in %s ===\n\n", project)
+	if date == "" {
+		fmt.Println("  No file history recorded yet")
+		return
+	}
+
+	entryDate, _ := time.Parse("2006-01-02", date)
+	fmt.Printf("As of %s:\n", entryDate.Format("Jan 2, 2006"))
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].file < changes[j].file })
+	for _, change := range changes {
+		if change.delta != 0 {
+			fmt.Printf("  %s: %d words (%+d)\n", change.file, change.words, change.delta)
+		} else {
+			fmt.Printf("  %s: %d words\n", change.file, change.words)
+		}
+	}
+	fmt.Printf("  Total: %d words\n", fileStats[date].Total)
+
+	showChapterStats(fileStats)
+}
+
+// showChapterStats displays the chapters of the most recent entry with their
+// share of the total and their change since the previous entry
+func showChapterStats(fileStats FileStatsFile) {
+	var dates []string
+	for date := range fileStats {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	latest := fileStats[dates[len(dates)-1]].Chapters
+	if len(latest) == 0 {
+		return
+	}
+
+	previous := latest
+	if len(dates) > 1 {
+		previous = fileStats[dates[len(dates)-2]].Chapters
+	}
+
+	// Match chapters by label, pairing repeated labels in order
+	previousWords := make(map[string][]int)
+	for _, chapter := range previous {
+		previousWords[chapter.Label] = append(previousWords[chapter.Label], chapter.Words)
+	}
+
+	total := 0
+	for _, chapter := range latest {
+		total += chapter.Words
+	}
+
+	fmt.Println("\nChapters:")
+	for _, chapter := range latest {
+		delta := chapter.Words
+		if words := previousWords[chapter.Label]; len(words) > 0 {
+			delta = chapter.Words - words[0]
+			previousWords[chapter.Label] = words[1:]
+		}
+
+		share := 0
+		if total > 0 {
+			share = chapter.Words * 100 / total
+		}
+
+		if delta != 0 {
+			fmt.Printf("  %s: %d words, %d%% (%+d)\n", chapter.Label, chapter.Words, share, delta)
+		} else {
+			fmt.Printf("  %s: %d words, %d%%\n", chapter.Label, chapter.Words, share)
+		}
+	}
+}
+
+// ShowFilesChangedToday lists the files that changed today in every project
+// that keeps a file history
+func ShowFilesChangedToday() {
+	dataDir, err := getDataDir()
+	if err != nil {
+		return
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dataDir, "files", "*_files.yaml"))
+	if len(paths) == 0 {
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	fmt.Println("\nFiles Worked On Today:")
+
+	found := false
+	for _, path := range paths {
+		project := strings.TrimSuffix(filepath.Base(path), "_files.yaml")
+		fileStats, err := LoadFileStats(project)
+		if err != nil {
+			continue
+		}
+
+		date, changes := latestFileChanges(fileStats)
+		if date != today {
+			continue
+		}
+		if name := fileStats[date].Name; name != "" {
+			project = name
+		}
+
+		sort.Slice(changes, func(i, j int) bool { return changes[i].delta > changes[j].delta })
+		for _, change := range changes {
+			if change.delta != 0 {
+				fmt.Printf("  %s/%s: %+d words\n", project, change.file, change.delta)
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		fmt.Println("  No file changes recorded today")
+	}
+}
+
+// fileHistoryKey returns the key of the file history whose latest entry
+// names the project, or "" when there is none
+func fileHistoryKey(dataDir, name string) string {
+	paths, _ := filepath.Glob(filepath.Join(dataDir, "files", "*_files.yaml"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var fileStats FileStatsFile
+		if err := yaml.Unmarshal(data, &fileStats); err != nil {
+			continue
+		}
+		if date, _ := latestFileChanges(fileStats); date != "" && fileStats[date].Name == name {
+			return strings.TrimSuffix(filepath.Base(path), "_files.yaml")
+		}
+	}
+	return ""
+}
+
+// latestFileChanges compares the most recent entry with the one before it.
+// Files that were removed are reported with zero words, and the first entry
+// is a baseline without changes.
+func latestFileChanges(fileStats FileStatsFile) (string, []fileChange) {
+	var dates []string
+	for date := range fileStats {
+		dates = append(dates, date)
+	}
+	if len(dates) == 0 {
+		return "", nil
+	}
+	sort.Strings(dates)
+
+	latest := fileStats[dates[len(dates)-1]].Files
+	previous := latest
+	if len(dates) > 1 {
+		previous = fileStats[dates[len(dates)-2]].Files
+	}
+
+	var changes []fileChange
+	for file, words := range latest {
+		changes = append(changes, fileChange{file: file, words: words, delta: words - previous[file]})
+	}
+	for file, words := range previous {
+		if _, exists := latest[file]; !exists {
+			changes = append(changes, fileChange{file: file, delta: -words})
+		}
+	}
+
+	return dates[len(dates)-1], changes
+}
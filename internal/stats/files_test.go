@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLatestFileChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    FileStatsFile
+		date     string
+		expected []fileChange
+	}{
+		{
+			name: "No history",
+		},
+		{
+			name:  "First entry is a baseline",
+			stats: FileStatsFile{"2026-01-01": {Files: map[string]int{"one.md": 100}}},
+			date:  "2026-01-01",
+			expected: []fileChange{
+				{file: "one.md", words: 100},
+			},
+		},
+		{
+			name: "Changed, added and removed files",
+			stats: FileStatsFile{
+				"2026-01-01": {Files: map[string]int{"one.md": 100, "two.md": 50, "old.md": 30}},
+				"2026-01-02": {Files: map[string]int{"one.md": 100, "two.md": 80}},
+				"2026-01-03": {Files: map[string]int{"one.md": 90, "two.md": 80, "three.md": 40}},
+			},
+			date: "2026-01-03",
+			expected: []fileChange{
+				{file: "one.md", words: 90, delta: -10},
+				{file: "three.md", words: 40, delta: 40},
+				{file: "two.md", words: 80},
+			},
+		},
+		{
+			name: "Removed file",
+			stats: FileStatsFile{
+				"2026-01-01": {Files: map[string]int{"one.md": 100, "old.md": 30}},
+				"2026-01-02": {Files: map[string]int{"one.md": 100}},
+			},
+			date: "2026-01-02",
+			expected: []fileChange{
+				{file: "old.md", delta: -30},
+				{file: "one.md", words: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, changes := latestFileChanges(tt.stats)
+			sort.Slice(changes, func(i, j int) bool { return changes[i].file < changes[j].file })
+			if date != tt.date || !reflect.DeepEqual(changes, tt.expected) {
+				t.Errorf("latestFileChanges() = %s, %+v, want %s, %+v", date, changes, tt.date, tt.expected)
+			}
+		})
+	}
+}
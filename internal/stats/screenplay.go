@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ScreenplayStats holds the metrics of a project's screenplays
+type ScreenplayStats struct {
+	Scenes        int     `yaml:"scenes"`
+	Pages         float64 `yaml:"pages"`
+	DialogueWords int     `yaml:"dialogue_words"`
+	ActionWords   int     `yaml:"action_words"`
+}
+
+// calculatePageDeltas returns the pages written each day in a project's
+// screenplays. The first entry of a project is its baseline.
+func calculatePageDeltas(stats StatsFile, project string) map[string]float64 {
+	var dates []string
+	for date := range stats {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	deltas := make(map[string]float64)
+	previous, seen := 0.0, false
+	for _, date := range dates {
+		screenplay, ok := stats[date].Screenplays[project]
+		if !ok {
+			continue
+		}
+		if seen {
+			deltas[date] = screenplay.Pages - previous
+		}
+		previous, seen = screenplay.Pages, true
+	}
+
+	return deltas
+}
+
+// sumPageDeltas adds up the pages written between two dates, inclusive
+func sumPageDeltas(deltas map[string]float64, start, end time.Time) float64 {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	total := 0.0
+	for date, delta := range deltas {
+		if date >= from && date <= to {
+			total += delta
+		}
+	}
+	return total
+}
+
+// showScreenplayStats displays the latest metrics of every project with
+// screenplays and the pages written today, this week and in the past 30 days
+func showScreenplayStats(stats StatsFile) {
+	var latestDate string
+	for date, dayStats := range stats {
+		if len(dayStats.Screenplays) > 0 && date > latestDate {
+			latestDate = date
+		}
+	}
+	if latestDate == "" {
+		return
+	}
+
+	latest := stats[latestDate].Screenplays
+	var projects []string
+	for project := range latest {
+		projects = append(projects, project)
+	}
+	sortByName(stats, projects)
+
+	now := time.Now()
+	weekStart, weekEnd := getCurrentWeekRange(now)
+
+	fmt.Println("\nScreenplays:")
+	for _, project := range projects {
+		screenplay := latest[project]
+		dialogue := 0
+		if words := screenplay.DialogueWords + screenplay.ActionWords; words > 0 {
+			dialogue = int(float64(screenplay.DialogueWords)*100/float64(words) + 0.5)
+		}
+
+		deltas := calculatePageDeltas(stats, project)
+		fmt.Printf("  %s: %.1f pages, %d scenes, %d%% dialogue / %d%% action\n",
+			projectName(stats, project), screenplay.Pages, screenplay.Scenes, dialogue, 100-dialogue)
+		fmt.Printf("    Pages today: %+.1f, this week: %+.1f, past 30 days: %+.1f\n",
+			sumPageDeltas(deltas, now, now),
+			sumPageDeltas(deltas, weekStart, weekEnd),
+			sumPageDeltas(deltas, now.AddDate(0, 0, -29), now))
+	}
+}
@@ -0,0 +1,30 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCalculatePageDeltas(t *testing.T) {
+	stats := StatsFile{
+		"2026-01-01": {Projects: map[string]int{"Notes": 100}},
+		// The first entry with the screenplay is its baseline
+		"2026-01-02": {Screenplays: map[string]ScreenplayStats{"Pilot": {Pages: 10}}},
+		"2026-01-03": {Screenplays: map[string]ScreenplayStats{"Pilot": {Pages: 12.5}, "Short": {Pages: 3}}},
+		"2026-01-04": {Projects: map[string]int{"Notes": 120}},
+		"2026-01-05": {Screenplays: map[string]ScreenplayStats{"Pilot": {Pages: 11.5}}},
+	}
+
+	expected := map[string]float64{"2026-01-03": 2.5, "2026-01-05": -1}
+	deltas := calculatePageDeltas(stats, "Pilot")
+	if !reflect.DeepEqual(deltas, expected) {
+		t.Errorf("calculatePageDeltas() = %v, want %v", deltas, expected)
+	}
+
+	start := time.Date(2026, 1, 3, 0, 0, 0, 0, time.Local)
+	end := time.Date(2026, 1, 4, 0, 0, 0, 0, time.Local)
+	if pages := sumPageDeltas(deltas, start, end); pages != 2.5 {
+		t.Errorf("sumPageDeltas() = %v, want 2.5", pages)
+	}
+}
@@ -11,9 +11,19 @@ import (
 )
 
 type DayStats struct {
-	Projects map[string]int `yaml:"projects"`
-	Total    int            `yaml:"total"`
-	Delta    int            `yaml:"delta,omitempty"`
+	Projects map[string]int    `yaml:"projects"`
+	Total    int               `yaml:"total"`
+	Delta    int               `yaml:"delta,omitempty"`
+	Methods  map[string]string `yaml:"methods,omitempty"`
+	Names    map[string]string `yaml:"names,omitempty"`
+
+	Extraction map[string]string `yaml:"extraction,omitempty"`
+
+	Screenplays map[string]ScreenplayStats `yaml:"screenplays,omitempty"`
+	Breakdowns  map[string]BreakdownStats  `yaml:"breakdowns,omitempty"`
+
+	Categories     map[string]int `yaml:"categories,omitempty"`
+	CategoryDeltas map[string]int `yaml:"category_deltas,omitempty"`
 }
 
 type StatsFile map[string]DayStats
@@ -65,12 +75,22 @@ func CalculateStats(stats StatsFile) {
 	// Calculate daily deltas (words actually written each day)
 	dailyDeltas := calculateDailyDeltas(stats)
 
+	// Manuscript words are shown apart from notes and research
+	var manuscriptDeltas map[string]int
+	if usesCategories(stats) {
+		manuscriptDeltas = calculateCategoryDeltas(stats, manuscriptCategory)
+	}
+
 	fmt.Print("\n=== Writing Statistics ===\n\n")
 
 	// Today's stats
 	if todayDelta, exists := dailyDeltas[today]; exists {
 		fmt.Printf("Today (%s):\n", today)
-		fmt.Printf("  Words written: %d\n\n", todayDelta)
+		fmt.Printf("  Words written: %d\n", todayDelta)
+		if manuscriptDeltas != nil {
+			fmt.Printf("  Manuscript words: %d\n", manuscriptDeltas[today])
+		}
+		fmt.Println()
 	} else {
 		fmt.Printf("Today (%s):\n", today)
 		fmt.Println("  No words written yet")
@@ -87,6 +107,7 @@ func CalculateStats(stats StatsFile) {
 
 	fmt.Printf("This Week (Mon %s to Sun %s):\n", weekStart.Format("Jan 2"), weekEnd.Format("Jan 2"))
 	fmt.Printf("  Total words: %d\n", weekStats.total)
+	showManuscriptWords(manuscriptDeltas, weekStart, weekEnd)
 	fmt.Printf("  Days with writing: %d/%d\n", weekStats.daysWithWriting, daysInWeek)
 	if weekStats.daysWithWriting > 0 {
 		fmt.Printf("  Daily average: %d words\n\n", weekStats.total/daysInWeek)
@@ -100,6 +121,7 @@ func CalculateStats(stats StatsFile) {
 
 	fmt.Println("Past 30 Days:")
 	fmt.Printf("  Total words: %d\n", thirtyDayStats.total)
+	showManuscriptWords(manuscriptDeltas, thirtyDaysAgo, now)
 	fmt.Printf("  Days with writing: %d/30\n", thirtyDayStats.daysWithWriting)
 	if thirtyDayStats.daysWithWriting > 0 {
 		fmt.Printf("  Daily average: %d words\n\n", thirtyDayStats.total/30)
@@ -114,6 +136,7 @@ func CalculateStats(stats StatsFile) {
 
 	fmt.Printf("Year to Date (%d):\n", now.Year())
 	fmt.Printf("  Total words: %d\n", ytdStats.total)
+	showManuscriptWords(manuscriptDeltas, yearStart, now)
 	fmt.Printf("  Days with writing: %d/%d\n", ytdStats.daysWithWriting, daysInYear)
 	if ytdStats.daysWithWriting > 0 {
 		fmt.Printf("  Daily average: %d words\n\n", ytdStats.total/daysInYear)
@@ -127,6 +150,7 @@ func CalculateStats(stats StatsFile) {
 
 	fmt.Println("Past 365 Days:")
 	fmt.Printf("  Total words: %d\n", yearStats.total)
+	showManuscriptWords(manuscriptDeltas, yearAgo, now)
 	fmt.Printf("  Days with writing: %d/365\n", yearStats.daysWithWriting)
 	if yearStats.daysWithWriting > 0 {
 		fmt.Printf("  Daily average: %d words\n\n", yearStats.total/365)
@@ -136,6 +160,23 @@ func CalculateStats(stats StatsFile) {
 
 	// Most productive days
 	showTopDaysFromDeltas(dailyDeltas, 5)
+
+	// Pages per day for screenwriters
+	showScreenplayStats(stats)
+
+	// Words per revision status, POV character and tag
+	showBreakdownStats(stats)
+
+	// Words per category, when projects sort their files into categories
+	if usesCategories(stats) {
+		showCategoryStats(stats, now)
+	}
+
+	// Warn about deltas that compare counts made with different strategies
+	showMethodChanges(findMethodChanges(stats))
+
+	// Note the days a project's Markdown extraction changed
+	showExtractionChanges(findExtractionChanges(stats))
 }
 
 // getCurrentWeekRange returns Monday to Sunday of the current week
@@ -163,8 +204,8 @@ type periodStats struct {
 	daysWithWriting int
 }
 
-// calculateDailyDeltas calculates the actual words written each day
-// using the Delta field if available, or by comparing to previous day's total
+// calculateDailyDeltas calculates the actual words written each day using
+// the Delta field. Days on which words were cut count as none written.
 func calculateDailyDeltas(stats StatsFile) map[string]int {
 	deltas := make(map[string]int)
 	
@@ -175,7 +216,7 @@ func calculateDailyDeltas(stats StatsFile) map[string]int {
 	}
 	sort.Strings(dates)
 	
-	// Use Delta field if available, otherwise calculate from totals
+	// Use Delta field, which leaves out changes in how projects were counted
 	for i, date := range dates {
 		dayStats := stats[date]
 		
@@ -186,14 +227,10 @@ func calculateDailyDeltas(stats StatsFile) map[string]int {
 			// First entry is baseline (0 words written that day)
 			deltas[date] = 0
 		} else {
-			// Calculate delta from previous total (for old data without Delta field)
-			prevTotal := stats[dates[i-1]].Total
-			currTotal := dayStats.Total
-			delta := currTotal - prevTotal
-			if delta < 0 {
-				delta = 0 // Handle cases where total might decrease
-			}
-			deltas[date] = delta
+			// Words cut are not counted against words written. The totals
+			// are not compared, since they include a change in a project's
+			// Markdown extraction that the delta leaves out.
+			deltas[date] = 0
 		}
 	}
 	
@@ -256,3 +293,111 @@ func showTopDaysFromDeltas(deltas map[string]int, limit int) {
 		fmt.Println("  No writing days recorded yet")
 	}
 }
+
+// methodChange records a project whose counting strategy or Markdown
+// extraction changed between entries
+type methodChange struct {
+	date    string
+	project string
+	from    string
+	to      string
+}
+
+// findMethodChanges walks the history in date order and reports every day on
+// which a project was counted with a different strategy than before. Entries
+// written before strategies were recorded used the heuristic.
+func findMethodChanges(stats StatsFile) []methodChange {
+	return findChanges(stats, func(day DayStats) map[string]string { return day.Methods }, "heuristic")
+}
+
+// findExtractionChanges reports every day on which a project's Markdown was
+// extracted differently than before. Entries written before extraction was
+// recorded counted raw Markdown.
+func findExtractionChanges(stats StatsFile) []methodChange {
+	return findChanges(stats, func(day DayStats) map[string]string { return day.Extraction }, "raw")
+}
+
+// findChanges walks the history in date order and reports every day on which
+// the value recorded for a project differs from its previous one. Projects
+// without a recorded value had the initial one.
+func findChanges(stats StatsFile, recorded func(DayStats) map[string]string, initial string) []methodChange {
+	var dates []string
+	for date := range stats {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	var changes []methodChange
+	lastMethods := make(map[string]string)
+
+	for _, date := range dates {
+		dayStats := stats[date]
+
+		var projects []string
+		for project := range dayStats.Projects {
+			projects = append(projects, project)
+		}
+		sort.Strings(projects)
+
+		for _, project := range projects {
+			method := recorded(dayStats)[project]
+			if method == "" {
+				method = initial
+			}
+
+			if last, seen := lastMethods[project]; seen && last != method {
+				changes = append(changes, methodChange{date: date, project: projectName(stats, project), from: last, to: method})
+			}
+			lastMethods[project] = method
+		}
+	}
+
+	return changes
+}
+
+// showMethodChanges prints the counting strategy changes found in the history
+func showMethodChanges(changes []methodChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Println("\nCounting Method Changes:")
+	fmt.Println("  Deltas on these days compare counts made with different methods")
+	for _, change := range changes {
+		date, _ := time.Parse("2006-01-02", change.date)
+		fmt.Printf("  %s: %s switched from %s to %s\n", date.Format("Jan 2, 2006"), change.project, change.from, change.to)
+	}
+}
+
+// showExtractionChanges prints the Markdown extraction changes found in the history
+func showExtractionChanges(changes []methodChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Println("\nMarkdown Extraction Changes:")
+	fmt.Println("  Changes in these projects' counts on these days are not counted as words written")
+	for _, change := range changes {
+		date, _ := time.Parse("2006-01-02", change.date)
+		fmt.Printf("  %s: %s switched from %s to %s\n", date.Format("Jan 2, 2006"), change.project, change.from, change.to)
+	}
+}
+
+// projectName returns the latest display name of the project recorded under
+// key, which is the name itself for projects without an ID
+func projectName(stats StatsFile, key string) string {
+	name, latestDate := key, ""
+	for date, dayStats := range stats {
+		if recorded, ok := dayStats.Names[key]; ok && date > latestDate {
+			name, latestDate = recorded, date
+		}
+	}
+	return name
+}
+
+// sortByName sorts project keys by their display names
+func sortByName(stats StatsFile, projects []string) {
+	sort.Slice(projects, func(i, j int) bool {
+		return projectName(stats, projects[i]) < projectName(stats, projects[j])
+	})
+}
@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculateDailyDeltas(t *testing.T) {
+	stats := StatsFile{
+		"2026-01-01": {Total: 1000},
+		"2026-01-02": {Total: 1200, Delta: 200},
+		"2026-01-03": {Total: 1200},
+		"2026-01-04": {Total: 1100, Delta: -100},
+		// A project's new Markdown extraction added 500 words to the total,
+		// which the delta leaves out, while 20 words were cut elsewhere
+		"2026-01-05": {Total: 1580, Delta: -20},
+	}
+
+	expected := map[string]int{"2026-01-01": 0, "2026-01-02": 200, "2026-01-03": 0, "2026-01-04": 0, "2026-01-05": 0}
+	if got := calculateDailyDeltas(stats); !reflect.DeepEqual(got, expected) {
+		t.Errorf("calculateDailyDeltas() = %v, want %v", got, expected)
+	}
+}
+
+func TestFindMethodChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    StatsFile
+		expected []methodChange
+	}{
+		{
+			name: "Same method throughout",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}, Methods: map[string]string{"Book": "unicode-words"}},
+				"2026-01-02": {Projects: map[string]int{"Book": 150}, Methods: map[string]string{"Book": "unicode-words"}},
+			},
+		},
+		{
+			name: "Entries before methods were recorded used the heuristic",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100, "Notes": 10}},
+				"2026-01-02": {Projects: map[string]int{"Book": 90, "Notes": 12}, Methods: map[string]string{"Book": "unicode-words", "Notes": "heuristic"}},
+			},
+			expected: []methodChange{{date: "2026-01-02", project: "Book", from: "heuristic", to: "unicode-words"}},
+		},
+		{
+			name: "Switched and switched back",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"a1": 100}, Methods: map[string]string{"a1": "cjk"}, Names: map[string]string{"a1": "Novel"}},
+				"2026-01-02": {Projects: map[string]int{"a1": 300}, Methods: map[string]string{"a1": "characters"}},
+				"2026-01-03": {Projects: map[string]int{"a1": 110}, Methods: map[string]string{"a1": "cjk"}},
+			},
+			expected: []methodChange{
+				{date: "2026-01-02", project: "Novel", from: "cjk", to: "characters"},
+				{date: "2026-01-03", project: "Novel", from: "characters", to: "cjk"},
+			},
+		},
+		{
+			name: "New project with its own method",
+			stats: StatsFile{
+				"2026-01-01": {Projects: map[string]int{"Book": 100}},
+				"2026-01-02": {Projects: map[string]int{"Book": 100, "Essay": 20}, Methods: map[string]string{"Essay": "unicode-words"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findMethodChanges(tt.stats); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("findMethodChanges() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindExtractionChanges(t *testing.T) {
+	stats := StatsFile{
+		// Counted before extraction was recorded, so raw
+		"2026-01-01": {Projects: map[string]int{"Book": 1000, "Notes": 100}, Total: 1100},
+		// The day's delta leaves out the changes of the projects whose
+		// extraction changed, and Notes kept raw Markdown
+		"2026-01-02": {Projects: map[string]int{"Book": 820, "Notes": 110}, Total: 930, Delta: 10,
+			Extraction: map[string]string{"Book": "prose", "Notes": "raw"}},
+		"2026-01-03": {Projects: map[string]int{"Book": 900, "Notes": 110}, Total: 1010, Delta: 0,
+			Extraction: map[string]string{"Book": "prose+code_blocks", "Notes": "raw"}},
+	}
+
+	expected := []methodChange{
+		{date: "2026-01-02", project: "Book", from: "raw", to: "prose"},
+		{date: "2026-01-03", project: "Book", from: "prose", to: "prose+code_blocks"},
+	}
+	if got := findExtractionChanges(stats); !reflect.DeepEqual(got, expected) {
+		t.Errorf("findExtractionChanges() = %+v, want %+v", got, expected)
+	}
+
+	// Neither change is counted as words written or cut
+	deltas := calculateDailyDeltas(stats)
+	if deltas["2026-01-02"] != 10 || deltas["2026-01-03"] != 0 {
+		t.Errorf("daily deltas = %v, want 10 and 0 after the baseline", deltas)
+	}
+	if methods := findMethodChanges(stats); len(methods) != 0 {
+		t.Errorf("findMethodChanges() = %+v, want none", methods)
+	}
+}